@@ -3,46 +3,307 @@ package board
 import (
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 const emptySpace = " "
 
+// Blocked is what a blocked space holds. No token can be placed there and no
+// line runs through it.
+const Blocked = "#"
+
+// Rules describe the shape of a board and how tokens are placed on it.
+type Rules struct {
+	Rows      int
+	Cols      int
+	WinLength int  // tokens in a row needed to win
+	Gravity   bool // tokens drop to the lowest empty row of their column
+	Exact     bool // a line longer than WinLength, an overline, doesn't win
+	Torus     bool // lines wrap around the edges, from the last column to the first and the bottom row to the top
+}
+
+// ClassicRules are the rules of tic-tac-toe: three in a row on a 3x3 board.
+var ClassicRules = Rules{Rows: 3, Cols: 3, WinLength: 3}
+
+// ConnectFourRules are the rules of Connect Four: four in a row on a 6x7
+// board, with tokens dropped into columns.
+var ConnectFourRules = Rules{Rows: 6, Cols: 7, WinLength: 4, Gravity: true}
+
+// GomokuRules are the rules of freestyle Gomoku: five or more in a row on a
+// 15x15 board. Standard Gomoku also sets Exact.
+var GomokuRules = Rules{Rows: 15, Cols: 15, WinLength: 5}
+
+// OrderChaosRules are the rules of Order and Chaos: exactly five in a row on
+// a 6x6 board, of either token.
+var OrderChaosRules = Rules{Rows: 6, Cols: 6, WinLength: 5, Exact: true}
+
+// Validate returns an error if no game can be played with the rules.
+func (r Rules) Validate() error {
+	if r.Rows < 1 || r.Cols < 1 {
+		return fmt.Errorf("the board needs at least one row and one column")
+	}
+	if r.WinLength < 1 || (r.WinLength > r.Rows && r.WinLength > r.Cols) {
+		return fmt.Errorf("%d in a row doesn't fit on a %dx%d board", r.WinLength, r.Rows, r.Cols)
+	}
+	if r.Exact && r.Torus {
+		// A line that wraps all the way round has no ends to check for an overline
+		return fmt.Errorf("exact lines can't be played on a torus")
+	}
+	return nil
+}
+
+// String describes the rules, e.g. "6x7, 4 in a row, gravity" or
+// "15x15, 5 in a row, exact".
+func (r Rules) String() string {
+	s := fmt.Sprintf("%dx%d, %d in a row", r.Rows, r.Cols, r.WinLength)
+	if r.Gravity {
+		s += ", gravity"
+	}
+	if r.Exact {
+		s += ", exact"
+	}
+	if r.Torus {
+		s += ", torus"
+	}
+	return s
+}
+
+// LineRule decides whether the tokens of a line win the game. Lines with an
+// empty space are never passed to it.
+type LineRule func(tokens []string) bool
+
+// SumsTo returns a LineRule for tokens that are numbers, under which a line
+// wins when its numbers add up to total.
+func SumsTo(total int) LineRule {
+	return func(tokens []string) bool {
+		sum := 0
+		for _, token := range tokens {
+			n, err := strconv.Atoi(token)
+			if err != nil {
+				return false
+			}
+			sum += n
+		}
+		return sum == total
+	}
+}
+
 type Board struct {
-	spaces [3][3]string
+	rules    Rules
+	spaces   [][]string
+	lines    []line
+	lineRule LineRule   // nil when a line must hold a single token
+	start    [][]string // the tokens InitBoard sets the spaces back to, nil for none
+}
+
+// space is the position of a single space on the board.
+type space struct {
+	row int
+	col int
 }
 
+// line is a run of spaces that wins the game for the player holding all of them.
+type line []space
+
 func NewBoard() *Board {
-	return &Board{}
+	return NewBoardWithRules(ClassicRules)
 }
 
+// NewBoardWithRules returns an empty board played with the given rules, which
+// must be valid.
+func NewBoardWithRules(rules Rules) *Board {
+	b := &Board{rules: rules, lines: winningLines(rules)}
+	b.spaces = make([][]string, rules.Rows)
+	for i := range b.spaces {
+		b.spaces[i] = make([]string, rules.Cols)
+	}
+	b.InitBoard()
+	return b
+}
+
+// Rules returns the rules the board is played with.
+func (b *Board) Rules() Rules {
+	return b.rules
+}
+
+// SetLineRule changes how CheckWin decides whether a line wins. A nil rule
+// restores the default, a line holding the same token all along. The rule
+// doesn't apply to CheckWinForPlayer, which always looks for the player's token.
+func (b *Board) SetLineRule(rule LineRule) {
+	b.lineRule = rule
+}
+
+// Rows returns the number of rows of the board.
+func (b *Board) Rows() int {
+	return b.rules.Rows
+}
+
+// Cols returns the number of columns of the board.
+func (b *Board) Cols() int {
+	return b.rules.Cols
+}
+
+// SetStartingBoard sets the spaces of a 3x3 board.
 func (b *Board) SetStartingBoard(postions [3][3]string) {
-	b.spaces = postions
+	for i := 0; i < 3; i++ {
+		copy(b.spaces[i], postions[i][:])
+	}
 }
 
+// InitBoard empties the board, or sets it back to the tokens of the layout it
+// was parsed from. Blocked spaces stay blocked.
 func (b *Board) InitBoard() {
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			b.spaces[i][j] = emptySpace
+	for i := range b.spaces {
+		for j := range b.spaces[i] {
+			switch {
+			case b.spaces[i][j] == Blocked:
+			case b.start != nil:
+				b.spaces[i][j] = b.start[i][j]
+			default:
+				b.spaces[i][j] = emptySpace
+			}
+		}
+	}
+}
+
+// StartsEmpty reports whether InitBoard empties the board, rather than
+// setting it back to the tokens of a layout.
+func (b *Board) StartsEmpty() bool {
+	return b.start == nil
+}
+
+// Block blocks the space at the given row and column for good, taking away
+// the lines through it.
+func (b *Board) Block(row int, col int) {
+	b.spaces[row][col] = Blocked
+
+	var lines []line
+	for _, l := range b.lines {
+		if !slices.Contains(l, space{row, col}) {
+			lines = append(lines, l)
+		}
+	}
+	b.lines = lines
+}
+
+// HasBlocked reports whether any space of the board is blocked.
+func (b *Board) HasBlocked() bool {
+	for i := range b.spaces {
+		if slices.Contains(b.spaces[i], Blocked) {
+			return true
 		}
 	}
+	return false
 }
 
-// RemoveToken removes the token at the given row and column.
+// ParseLayout returns a board played with the rules and laid out as given:
+// the rows from the top separated by '/', with '.' for an empty space, '#' for
+// a blocked one and one of the tokens for a token, e.g. "..#/.X./#.." on a
+// 3x3 board. The tokens stay on the board when it is initialized.
+func ParseLayout(rules Rules, layout string, tokens []string) (*Board, error) {
+	rows := strings.Split(layout, "/")
+	if len(rows) != rules.Rows {
+		return nil, fmt.Errorf("the layout has %d rows, expected %d", len(rows), rules.Rows)
+	}
+
+	b := NewBoardWithRules(rules)
+	for i, row := range rows {
+		spaces := []rune(row)
+		if len(spaces) != rules.Cols {
+			return nil, fmt.Errorf("row %d of the layout has %d spaces, expected %d", i, len(spaces), rules.Cols)
+		}
+		for j, r := range spaces {
+			switch r {
+			case '.':
+			case '#':
+				b.Block(i, j)
+			default:
+				if !slices.Contains(tokens, string(r)) {
+					return nil, fmt.Errorf("%q in row %d of the layout isn't a space or one of the tokens %s", r, i, strings.Join(tokens, ", "))
+				}
+				b.spaces[i][j] = string(r)
+				if b.start == nil {
+					b.start = make([][]string, rules.Rows)
+				}
+			}
+		}
+	}
+
+	if b.start != nil {
+		for i := range b.spaces {
+			b.start[i] = slices.Clone(b.spaces[i])
+		}
+	}
+	return b, nil
+}
+
+// Layout returns the board in the notation read by ParseLayout.
+func (b *Board) Layout() string {
+	rows := make([]string, len(b.spaces))
+	for i := range b.spaces {
+		rows[i] = strings.ReplaceAll(strings.Join(b.spaces[i], ""), emptySpace, ".")
+	}
+	return strings.Join(rows, "/")
+}
+
+// Clone returns a copy of the board that can be changed without changing b.
+func (b *Board) Clone() *Board {
+	clone := &Board{rules: b.rules, lines: b.lines, lineRule: b.lineRule, start: b.start}
+	clone.spaces = make([][]string, len(b.spaces))
+	for i := range b.spaces {
+		clone.spaces[i] = append([]string(nil), b.spaces[i]...)
+	}
+	return clone
+}
+
+// InBounds reports whether row and col are a space on the board.
+func (b *Board) InBounds(row int, col int) bool {
+	return row >= 0 && row < b.rules.Rows && col >= 0 && col < b.rules.Cols
+}
+
+// DropRow returns the row a token dropped into the column lands on, the
+// last empty one above the first token or blocked space, or -1 if the column
+// is full.
+func (b *Board) DropRow(col int) int {
+	row := -1
+	for row+1 < b.rules.Rows && b.spaces[row+1][col] == emptySpace {
+		row++
+	}
+	return row
+}
+
+// CanPlace reports whether a token can be placed at the given row and column.
+// With gravity, that is only the lowest empty space of the column.
+func (b *Board) CanPlace(row int, col int) bool {
+	if !b.InBounds(row, col) || b.spaces[row][col] != emptySpace {
+		return false
+	}
+	return !b.rules.Gravity || b.DropRow(col) == row
+}
+
+// RemoveToken removes the token at the given row and column. A blocked
+// space stays blocked.
 func (b *Board) RemoveToken(row int, col int) {
-	b.spaces[row][col] = emptySpace
+	if b.spaces[row][col] != Blocked {
+		b.spaces[row][col] = emptySpace
+	}
 }
 
 // PlaceToken places a move on the board at the given row and column
 // with the given value. Returns true if the move was successfully placed,
-// false if the space is already taken.
+// false if the space is already taken or, with gravity, the token would
+// not come to rest there.
 func (b *Board) PlaceToken(row int, col int, playerToken string) bool {
 
-	if row < 0 || row > 2 || col < 0 || col > 2 {
-		log.Printf("row and col must be between 0 and 2")
+	if !b.InBounds(row, col) {
+		log.Printf("row must be between 0 and %d and col between 0 and %d", b.rules.Rows-1, b.rules.Cols-1)
 		return false
 	}
 
-	if b.spaces[row][col] != emptySpace {
+	if !b.CanPlace(row, col) {
 		return false
 	}
 
@@ -51,15 +312,33 @@ func (b *Board) PlaceToken(row int, col int, playerToken string) bool {
 }
 
 func (b *Board) PrintBoard() {
+	b.PrintBoardWithLabels(nil)
+}
+
+// PrintBoardWithLabels prints the board like PrintBoard, but shows the label
+// of each empty space in place of the blank. The columns are widened to fit
+// the longest label. Labels are indexed by row and then column, and may be
+// nil or shorter than the board.
+func (b *Board) PrintBoardWithLabels(labels [][]string) {
+	width := len(strconv.Itoa(b.rules.Cols - 1))
+	for i := range labels {
+		for j := range labels[i] {
+			width = max(width, len(labels[i][j]))
+		}
+	}
+	rowWidth := len(strconv.Itoa(b.rules.Rows - 1))
+
+	header := make([]string, b.rules.Cols)
+	for col := range header {
+		header[col] = fmt.Sprintf("%-*d", width, col)
+	}
 
 	fmt.Println("")
-	fmt.Println("   0|1|2")
-	b.printHorizontalLine()
-	b.printRow(0)
-	b.printHorizontalLine()
-	b.printRow(1)
-	b.printHorizontalLine()
-	b.printRow(2)
+	fmt.Printf("%s%s\n", strings.Repeat(" ", rowWidth+2), strings.Join(header, "|"))
+	for row := 0; row < b.rules.Rows; row++ {
+		b.printHorizontalLine(rowWidth, width)
+		b.printRow(row, rowWidth, width, labels)
+	}
 	fmt.Println("")
 }
 
@@ -68,60 +347,133 @@ func (b *Board) GetToken(row int, col int) string {
 	return b.spaces[row][col]
 }
 
+// Key returns a string that identifies the position, for use as a map key.
+func (b *Board) Key() string {
+	var key strings.Builder
+	for i := range b.spaces {
+		for j := range b.spaces[i] {
+			key.WriteString(b.spaces[i][j])
+		}
+	}
+	return key.String()
+}
+
 // CheckWinForPlayer checks if the game has been won by a specific player.
 func (b *Board) CheckWinForPlayer(playerToken string) bool {
-	// Check rows
-	for i := 0; i < 3; i++ {
-		if b.compareSpaces(b.spaces[i][0], playerToken) && b.compareSpaces(b.spaces[i][1], playerToken) && b.compareSpaces(b.spaces[i][2], playerToken) {
+	for _, l := range b.lines {
+		if b.lineWins(l, playerToken) {
 			return true
 		}
 	}
-	// Check columns
-	for i := 0; i < 3; i++ {
-		if b.compareSpaces(b.spaces[0][i], playerToken) && b.compareSpaces(b.spaces[1][i], playerToken) && b.compareSpaces(b.spaces[2][i], playerToken) {
+	return false
+}
+
+// CheckWin checks if the game has been won by a player.
+func (b *Board) CheckWin() bool {
+	if b.lineRule != nil {
+		return b.checkLineRule()
+	}
+
+	for _, l := range b.lines {
+		if b.lineWins(l, b.spaces[l[0].row][l[0].col]) {
 			return true
 		}
 	}
-	// Check diagonals
-	if b.compareSpaces(b.spaces[0][0], playerToken) && b.compareSpaces(b.spaces[1][1], playerToken) && b.compareSpaces(b.spaces[2][2], playerToken) {
-		return true
-	}
+	return false
+}
 
-	if b.compareSpaces(b.spaces[0][2], playerToken) && b.compareSpaces(b.spaces[1][1], playerToken) && b.compareSpaces(b.spaces[2][0], playerToken) {
-		return true
+// checkLineRule checks if any full line wins under the line rule.
+func (b *Board) checkLineRule() bool {
+	tokens := make([]string, 0, b.rules.WinLength)
+	for _, l := range b.lines {
+		tokens = tokens[:0]
+		for _, s := range l {
+			if b.spaces[s.row][s.col] == emptySpace {
+				break
+			}
+			tokens = append(tokens, b.spaces[s.row][s.col])
+		}
+		if len(tokens) == len(l) && b.lineRule(tokens) {
+			return true
+		}
 	}
 	return false
 }
 
-// CheckWin checks if the game has been won by a player.
-func (b *Board) CheckWin() bool {
-	// Check rows
-	for i := 0; i < 3; i++ {
-		if b.compareSpaces(b.spaces[i][0], b.spaces[i][1]) && b.compareSpaces(b.spaces[i][1], b.spaces[i][2]) {
-			return true
+// EachLine calls f with the tokens on every run of WinLength spaces in a
+// row, column or diagonal, empty spaces included. The slice is reused
+// between calls.
+func (b *Board) EachLine(f func(tokens []string)) {
+	tokens := make([]string, b.rules.WinLength)
+	for _, l := range b.lines {
+		for i, s := range l {
+			tokens[i] = b.spaces[s.row][s.col]
 		}
+		f(tokens)
 	}
-	// Check columns
-	for i := 0; i < 3; i++ {
-		if b.compareSpaces(b.spaces[0][i], b.spaces[1][i]) && b.compareSpaces(b.spaces[1][i], b.spaces[2][i]) {
-			return true
+}
+
+// LinesThrough returns the number of winning lines through the space. On
+// the classic board that is 4 for the centre, 3 for a corner and 2 for the
+// other spaces.
+func (b *Board) LinesThrough(row int, col int) int {
+	lines := 0
+	for _, l := range b.lines {
+		if slices.Contains(l, space{row, col}) {
+			lines++
 		}
 	}
-	// Check diagonals
-	if b.compareSpaces(b.spaces[0][0], b.spaces[1][1]) && b.compareSpaces(b.spaces[1][1], b.spaces[2][2]) {
-		return true
+	return lines
+}
+
+// CheckWinAt checks if the token at the given row and column is part of a
+// winning line. Only the last token placed can have won the game, so this
+// is a quicker check than CheckWin on large boards.
+func (b *Board) CheckWinAt(row int, col int) bool {
+	if b.lineRule != nil {
+		return b.checkLineRule()
 	}
-	if b.compareSpaces(b.spaces[0][2], b.spaces[1][1]) && b.compareSpaces(b.spaces[1][1], b.spaces[2][0]) {
-		return true
+
+	token := b.spaces[row][col]
+	if token == emptySpace || token == Blocked {
+		return false
+	}
+
+	if b.rules.Torus {
+		// Runs wrap around the edges, so check the lines through the space
+		for _, l := range b.lines {
+			if slices.Contains(l, space{row, col}) && b.lineWins(l, token) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, d := range directions {
+		run := 1 + b.runLength(row, col, d, token) + b.runLength(row, col, space{-d.row, -d.col}, token)
+		if run == b.rules.WinLength || (run > b.rules.WinLength && !b.rules.Exact) {
+			return true
+		}
 	}
 	return false
 }
 
-// CheckTie checks if the game is a tie.
+// runLength counts the tokens in a row next to row, col in the direction.
+func (b *Board) runLength(row int, col int, d space, token string) int {
+	run := 0
+	for r, c := row+d.row, col+d.col; b.InBounds(r, c) && b.spaces[r][c] == token; r, c = r+d.row, c+d.col {
+		run++
+	}
+	return run
+}
+
+// CheckTie checks if the game is a tie, with no space left that a token can
+// be placed on. Blocked spaces never fill, so they don't count, and neither
+// do the empty spaces under one with gravity, which no token can reach.
 func (b *Board) CheckTie() bool {
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			if b.spaces[i][j] == " " {
+	for i := range b.spaces {
+		for j := range b.spaces[i] {
+			if b.CanPlace(i, j) {
 				return false
 			}
 		}
@@ -129,14 +481,115 @@ func (b *Board) CheckTie() bool {
 	return true
 }
 
+// lineWins reports whether every space of the line holds playerToken. With
+// exact rules the line must also not be part of a longer one.
+func (b *Board) lineWins(l line, playerToken string) bool {
+	for _, s := range l {
+		if !b.compareSpaces(b.spaces[s.row][s.col], playerToken) {
+			return false
+		}
+	}
+	if !b.rules.Exact || len(l) < 2 {
+		return true
+	}
+
+	first, last := l[0], l[len(l)-1]
+	d := space{l[1].row - first.row, l[1].col - first.col}
+	before, after := space{first.row - d.row, first.col - d.col}, space{last.row + d.row, last.col + d.col}
+	return !b.holds(before, playerToken) && !b.holds(after, playerToken)
+}
+
+// wrapLine wraps the spaces of l around the edges of a torus, in place. It returns
+// false if the line runs into itself, or if the same spaces were already
+// seen, which happens when a line is as long as the row or column it wraps.
+func wrapLine(l line, rules Rules, seen map[string]bool) bool {
+	keys := make([]int, len(l))
+	for k, s := range l {
+		l[k] = space{(s.row%rules.Rows + rules.Rows) % rules.Rows, (s.col%rules.Cols + rules.Cols) % rules.Cols}
+		keys[k] = l[k].row*rules.Cols + l[k].col
+	}
+
+	slices.Sort(keys)
+	if len(slices.Compact(slices.Clone(keys))) != len(keys) {
+		return false
+	}
+	key := fmt.Sprint(keys)
+	if seen[key] {
+		return false
+	}
+	seen[key] = true
+	return true
+}
+
+// holds reports whether the space is on the board and holds playerToken.
+func (b *Board) holds(s space, playerToken string) bool {
+	return b.InBounds(s.row, s.col) && b.spaces[s.row][s.col] == playerToken
+}
+
 func (b *Board) compareSpaces(s1 string, s2 string) bool {
 	return (s1 == s2) && (s1 != " ") && (s2 != " ")
 }
 
-func (b *Board) printHorizontalLine() {
-	fmt.Println("   -----")
+func (b *Board) printHorizontalLine(rowWidth int, width int) {
+	fmt.Printf("%s%s\n", strings.Repeat(" ", rowWidth+2), strings.Repeat("-", b.rules.Cols*(width+1)-1))
 }
 
-func (b *Board) printRow(row int) {
-	fmt.Printf(" %d %s|%s|%s \n", row, b.spaces[row][0], b.spaces[row][1], b.spaces[row][2])
+func (b *Board) printRow(row int, rowWidth int, width int, labels [][]string) {
+	cells := make([]string, b.rules.Cols)
+	for col := range cells {
+		cells[col] = fmt.Sprintf("%-*s", width, b.spaces[row][col])
+		if b.spaces[row][col] == emptySpace && row < len(labels) && col < len(labels[row]) && labels[row][col] != "" {
+			cells[col] = fmt.Sprintf("%-*s", width, labels[row][col])
+		}
+	}
+
+	// With gravity only the column is entered, so the rows aren't numbered
+	rowLabel := fmt.Sprintf("%*d", rowWidth, row)
+	if b.rules.Gravity {
+		rowLabel = strings.Repeat(" ", rowWidth)
+	}
+	fmt.Printf(" %s %s \n", rowLabel, strings.Join(cells, "|"))
+}
+
+// directions are the steps along a row, a column and the two diagonals.
+var directions = []space{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+
+// linesCache holds the winning lines of every Rules seen so far, as boards
+// with the same rules share them.
+var linesCache sync.Map
+
+// winningLines returns every run of WinLength spaces in a row, column or
+// diagonal of a board played with the rules. On a torus the runs wrap around
+// the edges, so a run that would leave the board carries on from the other
+// side.
+func winningLines(rules Rules) []line {
+	if lines, ok := linesCache.Load(rules); ok {
+		return lines.([]line)
+	}
+
+	var lines []line
+	seen := make(map[string]bool)
+	for row := 0; row < rules.Rows; row++ {
+		for col := 0; col < rules.Cols; col++ {
+			for _, d := range directions {
+				endRow := row + d.row*(rules.WinLength-1)
+				endCol := col + d.col*(rules.WinLength-1)
+				if !rules.Torus && (endRow < 0 || endRow >= rules.Rows || endCol < 0 || endCol >= rules.Cols) {
+					continue
+				}
+
+				l := make(line, rules.WinLength)
+				for k := range l {
+					l[k] = space{row + d.row*k, col + d.col*k}
+				}
+				if rules.Torus && !wrapLine(l, rules, seen) {
+					continue
+				}
+				lines = append(lines, l)
+			}
+		}
+	}
+
+	linesCache.Store(rules, lines)
+	return lines
 }
@@ -1,6 +1,8 @@
 package board
 
 import (
+	"bufio"
+	"strings"
 	"testing"
 )
 
@@ -65,7 +67,7 @@ func TestCheckWinForPlayer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b := &Board{spaces: tt.board}
+			b := newTestBoard(tt.board)
 			result := b.CheckWinForPlayer(tt.playerToken)
 			if result != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, result)
@@ -129,7 +131,7 @@ func TestGetToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b := &Board{spaces: tt.board}
+			b := newTestBoard(tt.board)
 			result := b.GetToken(tt.row, tt.col)
 			if result != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, result)
@@ -137,3 +139,393 @@ func TestGetToken(t *testing.T) {
 		})
 	}
 }
+
+func TestTransform(t *testing.T) {
+	b := newTestBoard([3][3]string{
+		{"X", "O", " "},
+		{" ", " ", " "},
+		{" ", " ", " "},
+	})
+
+	tests := []struct {
+		name     string
+		symmetry Symmetry
+		expected [3][3]string
+	}{
+		{
+			name:     "Rotate 90",
+			symmetry: Rotate90,
+			expected: [3][3]string{
+				{" ", " ", "X"},
+				{" ", " ", "O"},
+				{" ", " ", " "},
+			},
+		},
+		{
+			name:     "Rotate 180",
+			symmetry: Rotate180,
+			expected: [3][3]string{
+				{" ", " ", " "},
+				{" ", " ", " "},
+				{" ", "O", "X"},
+			},
+		},
+		{
+			name:     "Reflect horizontal",
+			symmetry: ReflectHorizontal,
+			expected: [3][3]string{
+				{" ", "O", "X"},
+				{" ", " ", " "},
+				{" ", " ", " "},
+			},
+		},
+		{
+			name:     "Reflect anti-diagonal",
+			symmetry: ReflectAntiDiagonal,
+			expected: [3][3]string{
+				{" ", " ", " "},
+				{" ", " ", "O"},
+				{" ", " ", "X"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := b.Transform(tt.symmetry)
+			if result.Key() != newTestBoard(tt.expected).Key() {
+				t.Errorf("expected %v, got %v", tt.expected, result.spaces)
+			}
+			if back := result.Transform(tt.symmetry.Inverse()); back.Key() != b.Key() {
+				t.Errorf("inverse did not restore the board, got %v", back.spaces)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	b := newTestBoard([3][3]string{
+		{" ", " ", " "},
+		{" ", "O", " "},
+		{" ", "X", " "},
+	})
+	canonical, symmetry := b.Canonical()
+
+	// Every orientation of the board has the same canonical form
+	for _, s := range Symmetries {
+		other, _ := b.Transform(s).Canonical()
+		if other.Key() != canonical.Key() {
+			t.Errorf("%v: expected %v, got %v", s, canonical.spaces, other.spaces)
+		}
+	}
+
+	// Moves on the canonical board map back to the original
+	row, col := symmetry.MapMove(2, 1)
+	if canonical.GetToken(row, col) != "X" {
+		t.Errorf("expected X at %d,%d of the canonical board", row, col)
+	}
+	row, col = symmetry.Inverse().MapMove(row, col)
+	if row != 2 || col != 1 {
+		t.Errorf("expected the move to map back to 2,1, got %d,%d", row, col)
+	}
+}
+
+// newTestBoard returns a classic board with the given spaces.
+func newTestBoard(spaces [3][3]string) *Board {
+	b := NewBoard()
+	b.SetStartingBoard(spaces)
+	return b
+}
+
+func TestWinningLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    Rules
+		expected int
+	}{
+		{name: "Classic", rules: ClassicRules, expected: 8},
+		{name: "Connect Four", rules: ConnectFourRules, expected: 69},
+		{name: "4x4, 3 in a row", rules: Rules{Rows: 4, Cols: 4, WinLength: 3}, expected: 24},
+		{name: "Torus", rules: Rules{Rows: 3, Cols: 3, WinLength: 3, Torus: true}, expected: 12},
+		{name: "4x4 torus, 3 in a row", rules: Rules{Rows: 4, Cols: 4, WinLength: 3, Torus: true}, expected: 64},
+		{name: "3x5 torus, 5 in a row", rules: Rules{Rows: 3, Cols: 5, WinLength: 5, Torus: true}, expected: 3 + 30}, // each row once, and the diagonals wrapping past the bottom
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if lines := winningLines(tt.rules); len(lines) != tt.expected {
+				t.Errorf("expected %d lines, got %d", tt.expected, len(lines))
+			}
+		})
+	}
+}
+
+func TestTorus(t *testing.T) {
+	b := NewBoardWithRules(Rules{Rows: 3, Cols: 3, WinLength: 3, Torus: true})
+	b.PlaceToken(0, 2, "X")
+	b.PlaceToken(1, 0, "X")
+	if b.CheckWinAt(1, 0) {
+		t.Fatal("expected no win with two tokens")
+	}
+	b.PlaceToken(2, 1, "X")
+	if !b.CheckWinAt(2, 1) || !b.CheckWinForPlayer("X") {
+		t.Error("expected 0,2, 1,0 and 2,1 to wrap into a diagonal")
+	}
+
+	classic := NewBoard()
+	classic.PlaceToken(0, 2, "X")
+	classic.PlaceToken(1, 0, "X")
+	classic.PlaceToken(2, 1, "X")
+	if classic.CheckWinAt(2, 1) {
+		t.Error("expected no wrapping without a torus")
+	}
+
+	if err := (Rules{Rows: 3, Cols: 3, WinLength: 3, Exact: true, Torus: true}).Validate(); err == nil {
+		t.Error("expected exact lines on a torus to be rejected")
+	}
+}
+
+func TestGravity(t *testing.T) {
+	b := NewBoardWithRules(ConnectFourRules)
+
+	if b.PlaceToken(0, 3, "X") {
+		t.Errorf("expected the token not to float at the top of the column")
+	}
+	for row := 5; row >= 0; row-- {
+		if got := b.DropRow(3); got != row {
+			t.Fatalf("expected the token to land on row %d, got %d", row, got)
+		}
+		if !b.PlaceToken(row, 3, "X") {
+			t.Fatalf("expected the token to be placed on row %d", row)
+		}
+	}
+	if got := b.DropRow(3); got != -1 {
+		t.Errorf("expected the full column to have no row, got %d", got)
+	}
+}
+
+func TestCheckWinConnectFour(t *testing.T) {
+	b := NewBoardWithRules(ConnectFourRules)
+
+	// A diagonal from the bottom left, held up by O's tokens
+	for col := 0; col < 4; col++ {
+		for row := 5; row > 5-col; row-- {
+			b.PlaceToken(row, col, "O")
+		}
+		if b.CheckWin() {
+			t.Fatalf("expected no win before the diagonal is complete")
+		}
+		b.PlaceToken(5-col, col, "X")
+	}
+	if !b.CheckWinForPlayer("X") {
+		t.Errorf("expected X to win with the diagonal")
+	}
+	if b.CheckWinForPlayer("O") {
+		t.Errorf("expected O not to win")
+	}
+}
+
+func TestCheckWinExact(t *testing.T) {
+	tests := []struct {
+		name     string
+		exact    bool
+		stones   int
+		expected bool
+	}{
+		{name: "Five, freestyle", exact: false, stones: 5, expected: true},
+		{name: "Five, exact", exact: true, stones: 5, expected: true},
+		{name: "Overline, freestyle", exact: false, stones: 6, expected: true},
+		{name: "Overline, exact", exact: true, stones: 6, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := GomokuRules
+			rules.Exact = tt.exact
+			b := NewBoardWithRules(rules)
+			for col := 0; col < tt.stones; col++ {
+				b.PlaceToken(7, 3+col, "X")
+			}
+			if result := b.CheckWinForPlayer("X"); result != tt.expected {
+				t.Errorf("CheckWinForPlayer: expected %v, got %v", tt.expected, result)
+			}
+			if result := b.CheckWinAt(7, 3); result != tt.expected {
+				t.Errorf("CheckWinAt: expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSumsTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    [3][3]string
+		expected bool
+	}{
+		{
+			name: "A row adding up to 15",
+			board: [3][3]string{
+				{"8", "1", "6"},
+				{" ", " ", " "},
+				{" ", " ", " "},
+			},
+			expected: true,
+		},
+		{
+			name: "A diagonal adding up to 15",
+			board: [3][3]string{
+				{"2", " ", "1"},
+				{" ", "5", " "},
+				{"3", " ", "8"},
+			},
+			expected: true,
+		},
+		{
+			name: "Full lines adding up to something else",
+			board: [3][3]string{
+				{"1", "2", "3"},
+				{"4", " ", " "},
+				{"7", " ", " "},
+			},
+			expected: false,
+		},
+		{
+			name: "Two numbers short of a line",
+			board: [3][3]string{
+				{"9", "6", " "},
+				{" ", " ", " "},
+				{" ", " ", " "},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBoard(tt.board)
+			b.SetLineRule(SumsTo(15))
+			if result := b.CheckWin(); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	b, err := ParseLayout(ClassicRules, "X.#/.#./X..", []string{"X", "O"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Layout(); got != "X.#/.#./X.." {
+		t.Errorf("expected the layout back, got %s", got)
+	}
+
+	if b.PlaceToken(1, 1, "O") {
+		t.Error("expected a blocked space to be rejected")
+	}
+	b.RemoveToken(1, 1)
+	if got := b.GetToken(1, 1); got != Blocked {
+		t.Errorf("expected the space to stay blocked, got %q", got)
+	}
+
+	// Only the left column and the bottom row are left
+	if len(b.lines) != 2 {
+		t.Errorf("expected 2 lines, got %d", len(b.lines))
+	}
+
+	b.PlaceToken(1, 0, "X")
+	if !b.CheckWin() || !b.CheckWinAt(1, 0) {
+		t.Error("expected the left column to win")
+	}
+
+	// The board goes back to its layout and fills up without the blocked spaces
+	b.InitBoard()
+	if got := b.Layout(); got != "X.#/.#./X.." || b.StartsEmpty() {
+		t.Errorf("expected the board to go back to its layout, got %s", got)
+	}
+	for _, s := range []space{{0, 1}, {1, 0}, {1, 2}, {2, 1}, {2, 2}} {
+		if b.CheckTie() {
+			t.Fatalf("expected no tie with %v empty", s)
+		}
+		b.PlaceToken(s.row, s.col, "O")
+	}
+	if !b.CheckTie() {
+		t.Error("expected a tie once every open space is filled")
+	}
+}
+
+func TestBlockedLines(t *testing.T) {
+	b, err := ParseLayout(ClassicRules, ".../.#./...", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The diagonals and the middle row and column all run through the centre
+	if len(b.lines) != 4 {
+		t.Errorf("expected 4 lines, got %d", len(b.lines))
+	}
+	for _, s := range b.Symmetries() {
+		if got := len(b.Transform(s).lines); got != 4 {
+			t.Errorf("expected the %v board to keep 4 lines, got %d", s, got)
+		}
+	}
+}
+
+func TestBlockedGravity(t *testing.T) {
+	b, err := ParseLayout(Rules{Rows: 4, Cols: 2, WinLength: 2, Gravity: true}, "../#./../..", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token dropped into the first column comes to rest on the blocked space
+	if got := b.DropRow(0); got != 0 {
+		t.Errorf("expected the token to land on row 0, got %d", got)
+	}
+	if got := b.DropRow(1); got != 3 {
+		t.Errorf("expected the token to land on row 3, got %d", got)
+	}
+
+	// The spaces under a blocked one can't be reached, so filling the other
+	// spaces ends the game in a draw
+	b, err = ParseLayout(Rules{Rows: 3, Cols: 3, WinLength: 3, Gravity: true}, "#../.../...", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, col := range []int{1, 2, 1, 2, 2, 1} {
+		if b.CheckTie() {
+			t.Fatalf("expected the game to go on before move %d", i)
+		}
+		b.PlaceToken(b.DropRow(col), col, []string{"X", "O"}[i%2])
+	}
+	if b.CheckWin() || !b.CheckTie() {
+		t.Errorf("expected %s to be a draw", b.Layout())
+	}
+}
+
+func TestParseLayoutErrors(t *testing.T) {
+	for _, layout := range []string{"../../..", ".../.../..", "..../.../...", "x../.../...", ".../.Z./..."} {
+		if _, err := ParseLayout(ClassicRules, layout, []string{"X", "O"}); err == nil {
+			t.Errorf("expected %q to be rejected", layout)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	rules := Rules{Rows: 4, Cols: 5, WinLength: 3, Torus: true}
+	header := Header("TEST", 2, rules, 7)
+	if header != "TEST 2 4 5 3 false false true 7\n" {
+		t.Errorf("unexpected header %q", header)
+	}
+
+	var extra int
+	read, err := ReadHeader(bufio.NewScanner(strings.NewReader(header)), "TEST", 2, &extra)
+	if err != nil || read != rules || extra != 7 {
+		t.Errorf("expected %v and 7, got %v, %d and %v", rules, read, extra, err)
+	}
+
+	for _, header := range []string{"", "OTHER 2 4 5 3 false false true 7", "TEST 1 4 5 3 false false true 7", "TEST 2 4 5 9 false false true 7"} {
+		if _, err := ReadHeader(bufio.NewScanner(strings.NewReader(header)), "TEST", 2, &extra); err == nil {
+			t.Errorf("expected %q to be rejected", header)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package board
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Header returns the first line of a file of positions played with the
+// rules: the magic that starts every file of its kind, the format version,
+// the rules and then the extra fields, separated by spaces.
+func Header(magic string, version int, rules Rules, extra ...any) string {
+	header := fmt.Sprintf("%s %d %d %d %d %t %t %t", magic, version, rules.Rows, rules.Cols, rules.WinLength, rules.Gravity, rules.Exact, rules.Torus)
+	for _, field := range extra {
+		header += fmt.Sprint(" ", field)
+	}
+	return header + "\n"
+}
+
+// ReadHeader reads a header written by Header from the scanner, checking its
+// magic and version, and returns its rules. The extra fields are scanned into
+// the pointers in extra.
+func ReadHeader(scanner *bufio.Scanner, magic string, version int, extra ...any) (Rules, error) {
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return Rules{}, err
+		}
+		return Rules{}, io.ErrUnexpectedEOF
+	}
+
+	var fileMagic string
+	var fileVersion int
+	var rules Rules
+	fields := append([]any{&fileMagic, &fileVersion, &rules.Rows, &rules.Cols, &rules.WinLength, &rules.Gravity, &rules.Exact, &rules.Torus}, extra...)
+	if _, err := fmt.Sscan(scanner.Text(), fields...); err != nil || fileMagic != magic {
+		return Rules{}, fmt.Errorf("not a %s file", magic)
+	}
+	if fileVersion != version {
+		return Rules{}, fmt.Errorf("unsupported %s version %d", magic, fileVersion)
+	}
+	return rules, rules.Validate()
+}
@@ -0,0 +1,142 @@
+package board
+
+// Symmetry is one of the 8 ways of rotating or reflecting the board onto
+// itself. Positions that map onto each other play the same way.
+type Symmetry int
+
+const (
+	Identity            Symmetry = iota
+	Rotate90                     // a quarter turn clockwise
+	Rotate180                    // a half turn
+	Rotate270                    // a quarter turn anticlockwise
+	ReflectHorizontal            // mirror the columns left to right
+	ReflectVertical              // mirror the rows top to bottom
+	ReflectDiagonal              // mirror across the top-left to bottom-right diagonal
+	ReflectAntiDiagonal          // mirror across the top-right to bottom-left diagonal
+)
+
+// Symmetries lists every symmetry of the board, starting with Identity.
+var Symmetries = [...]Symmetry{
+	Identity,
+	Rotate90,
+	Rotate180,
+	Rotate270,
+	ReflectHorizontal,
+	ReflectVertical,
+	ReflectDiagonal,
+	ReflectAntiDiagonal,
+}
+
+// MapMove returns where the space at row, col ends up once the symmetry is
+// applied to a 3x3 board.
+func (s Symmetry) MapMove(row int, col int) (int, int) {
+	return s.mapSpace(3, 3, row, col)
+}
+
+// mapSpace is MapMove for a board of any size. The rotations and diagonal
+// reflections only map a square board onto itself.
+func (s Symmetry) mapSpace(rows int, cols int, row int, col int) (int, int) {
+	lastRow, lastCol := rows-1, cols-1
+	switch s {
+	case Rotate90:
+		return col, lastRow - row
+	case Rotate180:
+		return lastRow - row, lastCol - col
+	case Rotate270:
+		return lastCol - col, row
+	case ReflectHorizontal:
+		return row, lastCol - col
+	case ReflectVertical:
+		return lastRow - row, col
+	case ReflectDiagonal:
+		return col, row
+	case ReflectAntiDiagonal:
+		return lastCol - col, lastRow - row
+	default:
+		return row, col
+	}
+}
+
+// Inverse returns the symmetry that undoes s.
+func (s Symmetry) Inverse() Symmetry {
+	switch s {
+	case Rotate90:
+		return Rotate270
+	case Rotate270:
+		return Rotate90
+	default:
+		return s
+	}
+}
+
+// Symmetries returns the symmetries that map the board onto itself without
+// changing how it plays. A square board has all 8, a rectangular one only
+// the half turn and the reflections, and with gravity the bottom row must
+// stay at the bottom, which leaves mirroring the columns.
+func (b *Board) Symmetries() []Symmetry {
+	switch {
+	case b.rules.Gravity:
+		return []Symmetry{Identity, ReflectHorizontal}
+	case b.rules.Rows != b.rules.Cols:
+		return []Symmetry{Identity, Rotate180, ReflectHorizontal, ReflectVertical}
+	default:
+		return Symmetries[:]
+	}
+}
+
+// MapMove returns where the space at row, col ends up once the symmetry is
+// applied to the board.
+func (b *Board) MapMove(s Symmetry, row int, col int) (int, int) {
+	return s.mapSpace(b.rules.Rows, b.rules.Cols, row, col)
+}
+
+// Transform returns a copy of the board with the symmetry applied, which must
+// be one of the board's Symmetries.
+func (b *Board) Transform(s Symmetry) *Board {
+	transformed := NewBoardWithRules(b.rules)
+	transformed.lineRule = b.lineRule
+	for i := range b.spaces {
+		for j := range b.spaces[i] {
+			row, col := b.MapMove(s, i, j)
+			if b.spaces[i][j] == Blocked {
+				transformed.Block(row, col)
+			} else {
+				transformed.spaces[row][col] = b.spaces[i][j]
+			}
+		}
+	}
+	return transformed
+}
+
+// Rotate returns a copy of the board turned a quarter turn clockwise.
+func (b *Board) Rotate() *Board {
+	return b.Transform(Rotate90)
+}
+
+// Reflect returns a copy of the board mirrored left to right.
+func (b *Board) Reflect() *Board {
+	return b.Transform(ReflectHorizontal)
+}
+
+// Canonical returns the representative of the board's symmetric positions,
+// which is the same for all of them, and the symmetry that turns the board
+// into it. Moves on the canonical board map back with the inverse symmetry.
+func (b *Board) Canonical() (*Board, Symmetry) {
+	canonical, symmetry := b.Transform(Identity), Identity
+	key := canonical.Key()
+	for _, s := range b.Symmetries()[1:] {
+		transformed := b.Transform(s)
+		if transformedKey := transformed.Key(); transformedKey < key {
+			canonical, symmetry, key = transformed, s, transformedKey
+		}
+	}
+	return canonical, symmetry
+}
+
+// CanonicalKey returns the key of the canonical board preceded by the token
+// of the player who just moved into the position. Symmetric positions reached
+// by the same player share it, so tables keyed by it learn about them at once.
+func (b *Board) CanonicalKey(mover string) string {
+	canonical, _ := b.Canonical()
+	return mover + canonical.Key()
+}
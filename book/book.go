@@ -0,0 +1,187 @@
+// Package book is an opening book: for the positions of the first plies of a
+// game, the results of the games that went through them, learned from
+// simulations and recorded games.
+package book
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/game"
+)
+
+// magic starts every book file, followed by the format version.
+const (
+	magic   = "TTTBOOK"
+	version = 1
+)
+
+// Record is the results of the games that went through a position, for the
+// player who moved into it.
+type Record struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Games returns the number of games in the record.
+func (r Record) Games() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+// Score is the share of the points the player earned, a draw counting half.
+// It is smoothed towards one half, so a position seen in few games is neither
+// trusted nor written off too soon.
+func (r Record) Score() float64 {
+	return (float64(r.Wins) + float64(r.Draws)/2 + 1) / float64(r.Games()+2)
+}
+
+// Move is a legal move with the record of the position it leads to.
+type Move struct {
+	Row    int
+	Col    int
+	Record Record
+}
+
+// Book holds the records of the positions reached in the first Plies plies
+// of games played with Rules.
+type Book struct {
+	Rules board.Rules
+	Plies int
+
+	// positions maps the board.CanonicalKey of each position to the record
+	// of the games that went through it. Moves are looked up by the position
+	// they lead to, so moves that lead to symmetric positions, or to the same
+	// one in another order, share it.
+	positions map[string]Record
+}
+
+// New returns an empty book for the first plies of games played with the rules.
+func New(rules board.Rules, plies int) *Book {
+	return &Book{Rules: rules, Plies: plies, positions: make(map[string]Record)}
+}
+
+// Len returns the number of canonical positions in the book.
+func (b *Book) Len() int {
+	return len(b.positions)
+}
+
+// Record replays a game from an empty board and adds its result to the
+// records of the positions reached in the first Plies plies. The winner is
+// the token of the player who won, or empty for a draw.
+func (b *Book) Record(history []game.Move, winner string) {
+	position := board.NewBoardWithRules(b.Rules)
+	for _, move := range history[:min(len(history), b.Plies)] {
+		position.PlaceToken(move.Row, move.Col, move.Token)
+
+		key := position.CanonicalKey(move.Token)
+		record := b.positions[key]
+		switch winner {
+		case "":
+			record.Draws++
+		case move.Token:
+			record.Wins++
+		default:
+			record.Losses++
+		}
+		b.positions[key] = record
+	}
+}
+
+// Moves returns every legal move of the player holding playerToken with its
+// record, which is empty for moves the book hasn't seen. It returns nil once
+// the game is past the book's plies, or if the board isn't played with the
+// book's rules.
+func (b *Book) Moves(position board.Board, playerToken string) []Move {
+	if position.Rules() != b.Rules || plies(position) >= b.Plies {
+		return nil
+	}
+
+	position = *position.Clone()
+	var moves []Move
+	for row := 0; row < position.Rows(); row++ {
+		for col := 0; col < position.Cols(); col++ {
+			if position.PlaceToken(row, col, playerToken) {
+				moves = append(moves, Move{row, col, b.positions[position.CanonicalKey(playerToken)]})
+				position.RemoveToken(row, col)
+			}
+		}
+	}
+	return moves
+}
+
+// Choose picks a move for the player holding playerToken at random, each
+// move weighted by the score of its record. Moves the book hasn't seen score
+// a half, so a new book spreads its games over every opening and learns which
+// of them lose. It returns false when the book has no moves for the position.
+func (b *Book) Choose(position board.Board, playerToken string) (int, int, bool) {
+	moves := b.Moves(position, playerToken)
+	if len(moves) == 0 {
+		return -1, -1, false
+	}
+
+	total := 0.0
+	for _, move := range moves {
+		total += move.Record.Score()
+	}
+	pick := rand.Float64() * total
+	for _, move := range moves {
+		if pick -= move.Record.Score(); pick < 0 {
+			return move.Row, move.Col, true
+		}
+	}
+	last := moves[len(moves)-1]
+	return last.Row, last.Col, true
+}
+
+// plies counts the tokens placed on the board.
+func plies(position board.Board) int {
+	n := 0
+	for row := 0; row < position.Rows(); row++ {
+		for col := 0; col < position.Cols(); col++ {
+			if token := position.GetToken(row, col); token != " " && token != board.Blocked {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// WriteTo writes the book as text: a board.Header with the plies, and then a
+// line for each position, sorted, with its quoted key and its record.
+func (b *Book) WriteTo(w io.Writer) (int64, error) {
+	var lines []string
+	for key, record := range b.positions {
+		lines = append(lines, fmt.Sprintf("%q %d %d %d\n", key, record.Wins, record.Draws, record.Losses))
+	}
+	sort.Strings(lines)
+
+	n, err := io.WriteString(w, board.Header(magic, version, b.Rules, b.Plies)+strings.Join(lines, ""))
+	return int64(n), err
+}
+
+// Read reads a book written by WriteTo.
+func Read(r io.Reader) (*Book, error) {
+	scanner := bufio.NewScanner(r)
+	var plies int
+	rules, err := board.ReadHeader(scanner, magic, version, &plies)
+	if err != nil {
+		return nil, err
+	}
+
+	b := New(rules, plies)
+	for line := 2; scanner.Scan(); line++ {
+		var key string
+		var record Record
+		if _, err := fmt.Sscanf(scanner.Text(), "%q %d %d %d", &key, &record.Wins, &record.Draws, &record.Losses); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		b.positions[key] = record
+	}
+	return b, scanner.Err()
+}
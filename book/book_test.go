@@ -0,0 +1,81 @@
+package book
+
+import (
+	"testing"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/internal/gametest"
+)
+
+func TestRecordSharesSymmetricPositions(t *testing.T) {
+	b := New(board.ClassicRules, 2)
+	b.Record(gametest.CornerGame, "X")
+
+	// Every corner opening is the same opening turned around
+	for _, move := range b.Moves(*board.NewBoard(), "X") {
+		corner := move.Row != 1 && move.Col != 1
+		if won := move.Record == (Record{Wins: 1}); won != corner {
+			t.Errorf("move %d,%d: unexpected record %+v", move.Row, move.Col, move.Record)
+		}
+	}
+
+	// O's reply to the bottom right corner mirrors its reply to the top left
+	position := board.NewBoard()
+	position.PlaceToken(2, 2, "X")
+	for _, move := range b.Moves(*position, "O") {
+		reply := (move.Row == 2 && move.Col == 1) || (move.Row == 1 && move.Col == 2)
+		if lost := move.Record == (Record{Losses: 1}); lost != reply {
+			t.Errorf("move %d,%d: unexpected record %+v", move.Row, move.Col, move.Record)
+		}
+	}
+
+	// The third ply is past the book
+	position.PlaceToken(1, 2, "O")
+	if moves := b.Moves(*position, "X"); moves != nil {
+		t.Errorf("expected no moves past the book, got %v", moves)
+	}
+	if b.Moves(*board.NewBoardWithRules(board.ConnectFourRules), "X") != nil {
+		t.Error("expected no moves for other rules")
+	}
+}
+
+func TestChooseWeighsLosingMovesDown(t *testing.T) {
+	b := New(board.ClassicRules, 1)
+	for i := 0; i < 1000; i++ {
+		b.Record(gametest.CornerGame[:1], "O")
+	}
+
+	// Choose picks at random, so check the weights it picks by instead
+	moves := b.Moves(*board.NewBoard(), "X")
+	total := 0.0
+	for _, move := range moves {
+		total += move.Record.Score()
+	}
+	for _, move := range moves {
+		share := move.Record.Score() / total
+		if corner := move.Row != 1 && move.Col != 1; corner && share > 0.001 {
+			t.Errorf("expected the losing corner %d,%d to be picked almost never, got a share of %v", move.Row, move.Col, share)
+		} else if !corner && share < 0.19 {
+			t.Errorf("expected the unseen move %d,%d to be picked about a fifth of the time, got a share of %v", move.Row, move.Col, share)
+		}
+	}
+
+	if _, _, ok := b.Choose(*board.NewBoard(), "X"); !ok {
+		t.Error("expected a book move")
+	}
+	position := board.NewBoard()
+	position.PlaceToken(1, 1, "X")
+	if _, _, ok := b.Choose(*position, "O"); ok {
+		t.Error("expected no book move past the book")
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	b := New(board.Rules{Rows: 4, Cols: 4, WinLength: 3, Torus: true}, 3)
+	b.Record(gametest.CornerGame, "")
+
+	read := gametest.ReadsBack(t, b, Read)
+	if read.Rules != b.Rules || read.Plies != b.Plies || read.Len() != 3 {
+		t.Errorf("expected %v covering %d plies with 3 positions, got %v, %d and %d", b.Rules, b.Plies, read.Rules, read.Plies, read.Len())
+	}
+}
@@ -2,6 +2,9 @@ package game
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/jackmcdermo/tic-tac-toe-/board"
 )
@@ -15,6 +18,8 @@ const (
 	XWin
 	OWin
 	Tie
+	WrongToken
+	Win // a player won a game of more than two players, see Winner
 )
 
 type Player struct {
@@ -22,6 +27,7 @@ type Player struct {
 	IsAI               bool   // true if player is AI
 	AiPlayerDifficulty int    // 0 - 9 (9 is hardest)
 	Name               string
+	Learned            bool // moves are picked by the learned agent instead of the search
 }
 
 func NewPlayer(token string, isAI bool, aiPlayerDifficulty int, name string) Player {
@@ -33,72 +39,204 @@ func NewPlayer(token string, isAI bool, aiPlayerDifficulty int, name string) Pla
 	}
 }
 
+// Move is a token placed on the board during a game.
+type Move struct {
+	Row   int
+	Col   int
+	Token string
+}
+
+// Game is a game between two or more players, who take turns in the order
+// they are listed. Misère, Wild, Numerical and Order and Chaos rules are only
+// for two players.
 type Game struct {
-	Board          *board.Board
-	Player1        Player
-	Player2        Player
-	nextMovePlayer *Player
-	RandomAI       bool
+	Board     *board.Board
+	Players   []Player
+	next      int // index of the player to move
+	winner    int // index of the winner, -1 while nobody has won
+	RandomAI  bool
+	Misere    bool // completing three in a row loses instead of winning
+	Wild      bool // either player may place X or O, and a line counts for whoever completed it
+	Numerical bool // X places the odd numbers and O the even ones, and a line adding up to 15 wins
+
+	// OrderChaos has both players place X or O. The player holding
+	// OrderToken plays Order and wins with five in a row of either, the
+	// other plays Chaos and wins by filling the board without one.
+	OrderChaos bool
+
+	history []Move
 }
 
 func NewGame(players []Player, randomAI bool) *Game {
 	return &Game{
 		Board:    board.NewBoard(),
-		Player1:  players[0],
-		Player2:  players[1],
+		Players:  players,
+		winner:   -1,
 		RandomAI: randomAI,
 	}
 }
 
 func (g *Game) AwaitingAI() bool {
-	return g.nextMovePlayer.IsAI
+	return g.Players[g.next].IsAI
 }
 
 func (g *Game) NextMovePlayer() Player {
-	return *g.nextMovePlayer
+	return g.Players[g.next]
+}
+
+// Winner returns the player who won the game, and false if nobody has.
+func (g *Game) Winner() (Player, bool) {
+	if g.winner == -1 {
+		return Player{}, false
+	}
+	return g.Players[g.winner], true
+}
+
+// playerIndex returns the index of the player holding token.
+func (g *Game) playerIndex(token string) int {
+	return slices.IndexFunc(g.Players, func(p Player) bool { return p.Token == token })
+}
+
+// Opponents returns the tokens of the other players, in the order they move
+// after the given one.
+func (g *Game) Opponents(player Player) []string {
+	i := g.playerIndex(player.Token)
+	var tokens []string
+	for j := 1; j < len(g.Players); j++ {
+		tokens = append(tokens, g.Players[(i+j)%len(g.Players)].Token)
+	}
+	return tokens
+}
+
+// History returns the moves played so far, in order.
+func (g *Game) History() []Move {
+	return g.history
+}
+
+// NumericalSum is what the numbers on a line add up to for a win in Numerical games.
+const NumericalSum = 15
+
+// NumberTokens returns the numbers from 1 to 9 that the player holding
+// playerToken may still place in a Numerical game: the odd ones for X and
+// the even ones for O, each only once.
+func NumberTokens(b *board.Board, playerToken string) []string {
+	used := make(map[string]bool)
+	for i := 0; i < b.Rows(); i++ {
+		for j := 0; j < b.Cols(); j++ {
+			used[b.GetToken(i, j)] = true
+		}
+	}
+
+	first := 1
+	if playerToken != "X" {
+		first = 2
+	}
+	var tokens []string
+	for n := first; n <= 9; n += 2 {
+		if token := strconv.Itoa(n); !used[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// OrderToken is the token of the player who plays Order in Order and Chaos.
+// The player holding the other one plays Chaos.
+const OrderToken = "X"
+
+// PicksToken reports whether the players pick the token they place on each
+// move, rather than always placing their own.
+func (g *Game) PicksToken() bool {
+	return g.Wild || g.Numerical || g.OrderChaos
+}
+
+// Tokens returns the tokens the player may place: their own, X or O in Wild
+// and Order and Chaos games, or their unused numbers in Numerical games.
+func (g *Game) Tokens(player Player) []string {
+	switch {
+	case g.Numerical:
+		return NumberTokens(g.Board, player.Token)
+	case g.Wild || g.OrderChaos:
+		return []string{"X", "O"}
+	default:
+		return []string{player.Token}
+	}
 }
 
 func (g *Game) InitGame() {
 	g.Board.InitBoard()
+	if g.Numerical {
+		g.Board.SetLineRule(board.SumsTo(NumericalSum))
+	}
+	g.history = nil
+	g.winner = -1
 	if g.getPlayerOneStartsFirst() {
-		g.nextMovePlayer = &g.Player1
+		g.next = 0
 	} else {
-		g.nextMovePlayer = &g.Player2
+		g.next = 1
 	}
 }
 
 func (g *Game) PrintMovePrompt() {
 
-	if g.nextMovePlayer.IsAI {
+	player := g.Players[g.next]
+	if player.IsAI {
 		fmt.Println("Press enter for the AI player to go...")
 	} else {
-		fmt.Printf("%s, enter your move (row,col): ", g.nextMovePlayer.Name)
+		format := "row,col"
+		if g.Board.Rules().Gravity {
+			format = "col"
+		}
+		if g.PicksToken() {
+			format += "," + strings.Join(g.Tokens(player), " or ")
+		}
+		fmt.Printf("%s, enter your move (%s): ", player.Name, format)
 	}
 }
 
 func (g *Game) DoMove(row int, col int) MoveResult {
-	var player Player
+	return g.DoMoveWithToken(row, col, g.Players[g.next].Token)
+}
+
+// DoMoveWithToken is DoMove placing the given token, which must be one of the
+// player's Tokens.
+func (g *Game) DoMoveWithToken(row int, col int, token string) MoveResult {
+	player := g.Players[g.next]
+
+	if !slices.Contains(g.Tokens(player), token) {
+		return WrongToken
+	}
 
 	// Place the move on the board. If the move was
 	// successful, check if the game is over.
-	if g.Board.PlaceToken(row, col, g.nextMovePlayer.Token) {
-		// Check if the move resulted in a win
+	if g.Board.PlaceToken(row, col, token) {
+		g.history = append(g.history, Move{row, col, token})
+
+		// Check if the move resulted in a win, which is the mover's
+		// whatever the token on the line. In misère the player who
+		// completed the line loses instead, and in Order and Chaos a
+		// line is always Order's.
 		if g.Board.CheckWin() {
-			if player.Token == "X" {
-				return XWin
-			} else {
-				return OWin
+			switch {
+			case g.OrderChaos:
+				g.winner = g.playerIndex(OrderToken)
+			case g.Misere:
+				g.winner = 1 - g.next
+			default:
+				g.winner = g.next
 			}
+			return g.winResult()
 		} else if g.Board.CheckTie() {
+			// Chaos wins by filling the board
+			if g.OrderChaos {
+				g.winner = 1 - g.playerIndex(OrderToken)
+				return g.winResult()
+			}
 			return Tie
 		}
 
-		// Switch players
-		if g.nextMovePlayer == &g.Player1 {
-			g.nextMovePlayer = &g.Player2
-		} else {
-			g.nextMovePlayer = &g.Player1
-		}
+		// Pass the turn to the next player
+		g.next = (g.next + 1) % len(g.Players)
 
 		return ValidMove
 
@@ -107,6 +245,18 @@ func (g *Game) DoMove(row int, col int) MoveResult {
 	}
 }
 
+// winResult returns the MoveResult for the winner.
+func (g *Game) winResult() MoveResult {
+	switch {
+	case len(g.Players) > 2:
+		return Win
+	case g.Players[g.winner].Token == "X":
+		return XWin
+	default:
+		return OWin
+	}
+}
+
 func (g *Game) getPlayerOneStartsFirst() bool {
 	// randomNum := rand.Float64()*2 - 1
 	// return randomNum >= 0
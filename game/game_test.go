@@ -0,0 +1,176 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+)
+
+func TestDoMoveWithToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		wild      bool
+		numerical bool
+		misere    bool
+		moves     []Move // the token is the one placed, players alternate starting with X
+		expected  MoveResult
+	}{
+		{
+			name:     "X completes a line of X",
+			moves:    []Move{{0, 0, "X"}, {1, 0, "O"}, {0, 1, "X"}, {1, 1, "O"}, {0, 2, "X"}},
+			expected: XWin,
+		},
+		{
+			name:     "O completes a line of O",
+			moves:    []Move{{0, 0, "X"}, {1, 0, "O"}, {0, 1, "X"}, {1, 1, "O"}, {2, 2, "X"}, {1, 2, "O"}},
+			expected: OWin,
+		},
+		{
+			name:     "Misère, X completes a line of X",
+			misere:   true,
+			moves:    []Move{{0, 0, "X"}, {1, 0, "O"}, {0, 1, "X"}, {1, 1, "O"}, {0, 2, "X"}},
+			expected: OWin,
+		},
+		{
+			name:     "Placing the opponent's token",
+			moves:    []Move{{0, 0, "O"}},
+			expected: WrongToken,
+		},
+		{
+			name:     "Wild, X completes a line of O",
+			wild:     true,
+			moves:    []Move{{0, 0, "O"}, {2, 2, "X"}, {0, 1, "O"}, {2, 0, "X"}, {0, 2, "O"}},
+			expected: XWin,
+		},
+		{
+			name:      "Numerical, X makes 15",
+			numerical: true,
+			moves:     []Move{{0, 0, "7"}, {1, 1, "2"}, {0, 1, "3"}, {2, 2, "4"}, {0, 2, "5"}},
+			expected:  XWin,
+		},
+		{
+			name:      "Numerical, O completes a line X started",
+			numerical: true,
+			moves:     []Move{{0, 0, "9"}, {0, 1, "2"}, {2, 2, "1"}, {0, 2, "4"}},
+			expected:  OWin,
+		},
+		{
+			name:      "Numerical, X placing an even number",
+			numerical: true,
+			moves:     []Move{{0, 0, "2"}},
+			expected:  WrongToken,
+		},
+		{
+			name:      "Numerical, a number used twice",
+			numerical: true,
+			moves:     []Move{{0, 0, "1"}, {1, 1, "2"}, {2, 2, "1"}},
+			expected:  WrongToken,
+		},
+		{
+			name:     "Wild, placing something else",
+			wild:     true,
+			moves:    []Move{{0, 0, "Z"}},
+			expected: WrongToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGame([]Player{NewPlayer("X", false, 0, "Player 1"), NewPlayer("O", false, 0, "Player 2")}, false)
+			g.Wild = tt.wild
+			g.Numerical = tt.numerical
+			g.Misere = tt.misere
+			g.InitGame()
+
+			var result MoveResult
+			for _, move := range tt.moves {
+				result = g.DoMoveWithToken(move.Row, move.Col, move.Token)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestThreePlayers(t *testing.T) {
+	g := NewGame([]Player{
+		NewPlayer("X", false, 0, "Player 1"),
+		NewPlayer("O", false, 0, "Player 2"),
+		NewPlayer("△", false, 0, "Player 3"),
+	}, false)
+	g.Board = board.NewBoardWithRules(board.Rules{Rows: 5, Cols: 5, WinLength: 3})
+	g.InitGame()
+
+	if opponents := g.Opponents(g.Players[1]); !slices.Equal(opponents, []string{"△", "X"}) {
+		t.Errorf("expected O's opponents to be △ and X, got %v", opponents)
+	}
+
+	// The players take turns in order, and the third one completes a line
+	moves := [][2]int{{0, 0}, {1, 0}, {4, 0}, {0, 1}, {1, 1}, {4, 1}, {3, 3}, {2, 2}, {4, 2}}
+	for i, move := range moves {
+		if token := g.NextMovePlayer().Token; token != g.Players[i%3].Token {
+			t.Fatalf("move %d: expected %s to move, got %s", i, g.Players[i%3].Token, token)
+		}
+		result := g.DoMove(move[0], move[1])
+		if i < len(moves)-1 && result != ValidMove {
+			t.Fatalf("move %d: expected a valid move, got %v", i, result)
+		} else if i == len(moves)-1 && result != Win {
+			t.Fatalf("expected a win, got %v", result)
+		}
+	}
+
+	if winner, ok := g.Winner(); !ok || winner.Token != "△" {
+		t.Errorf("expected △ to win, got %v %v", winner, ok)
+	}
+}
+
+func TestOrderAndChaos(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    board.Rules
+		moves    []Move
+		expected MoveResult
+	}{
+		{
+			name:     "Chaos completing a line loses",
+			rules:    board.Rules{Rows: 2, Cols: 2, WinLength: 2},
+			moves:    []Move{{0, 0, "O"}, {0, 1, "O"}},
+			expected: XWin,
+		},
+		{
+			name:  "Chaos wins on a full board",
+			rules: board.ClassicRules,
+			moves: []Move{
+				{0, 0, "X"}, {0, 1, "O"}, {0, 2, "X"},
+				{1, 0, "X"}, {1, 1, "O"}, {1, 2, "O"},
+				{2, 0, "O"}, {2, 1, "X"}, {2, 2, "X"},
+			},
+			expected: OWin,
+		},
+		{
+			name:     "Order placing O",
+			rules:    board.OrderChaosRules,
+			moves:    []Move{{0, 0, "O"}},
+			expected: ValidMove,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGame([]Player{NewPlayer(OrderToken, false, 0, "Order"), NewPlayer("O", false, 0, "Chaos")}, false)
+			g.Board = board.NewBoardWithRules(tt.rules)
+			g.OrderChaos = true
+			g.InitGame()
+
+			var result MoveResult
+			for _, move := range tt.moves {
+				result = g.DoMoveWithToken(move.Row, move.Col, move.Token)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+// Package gomoku implements Gomoku, five in a row on a 15x15 or 19x19 board.
+// In freestyle Gomoku six or more in a row also win, while in standard Gomoku
+// such an overline doesn't count and it takes exactly five.
+//
+// The board is far too large to search every open space, so the AI only
+// considers the spaces near the stones already played, strongest first, and
+// scores positions by the runs of five spaces each player can still fill.
+package gomoku
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+)
+
+// WinLength is the number of stones in a row needed to win.
+const WinLength = 5
+
+// reach is how far from the stones on the board a move is still considered.
+const reach = 2
+
+// candidateLimit is the number of moves searched in each position.
+const candidateLimit = 12
+
+// Move is a space on the board.
+type Move struct {
+	Row int
+	Col int
+}
+
+type Game struct {
+	board   *board.Board
+	size    int
+	toMove  int // index of the player to move, 0 for X
+	history []Move
+	winner  int // index of the winner, -1 while nobody has won
+
+	windows        [][WinLength]Move // every run of five spaces
+	windowsThrough [][]int           // the windows through each space
+	counts         [][2]int          // the stones of each player in each window
+	near           []int             // the stones within reach of each space
+	score          [2]int            // the weights of the windows only one player has stones in
+}
+
+// NewGame returns a game on a size x size board. With exact set, only
+// exactly five in a row wins.
+func NewGame(size int, exact bool) *Game {
+	g := &Game{
+		board:          board.NewBoardWithRules(board.Rules{Rows: size, Cols: size, WinLength: WinLength, Exact: exact}),
+		size:           size,
+		winner:         -1,
+		windowsThrough: make([][]int, size*size),
+		near:           make([]int, size*size),
+	}
+
+	directions := []Move{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for r := 0; r < size; r++ {
+		for c := 0; c < size; c++ {
+			for _, d := range directions {
+				if !g.inBounds(Move{r + d.Row*(WinLength-1), c + d.Col*(WinLength-1)}) {
+					continue
+				}
+
+				var window [WinLength]Move
+				for i := range window {
+					window[i] = Move{r + d.Row*i, c + d.Col*i}
+					g.windowsThrough[g.index(window[i])] = append(g.windowsThrough[g.index(window[i])], len(g.windows))
+				}
+				g.windows = append(g.windows, window)
+			}
+		}
+	}
+	g.counts = make([][2]int, len(g.windows))
+	return g
+}
+
+func (g *Game) inBounds(m Move) bool {
+	return m.Row >= 0 && m.Row < g.size && m.Col >= 0 && m.Col < g.size
+}
+
+// index returns the position of the space in the per-space slices.
+func (g *Game) index(m Move) int {
+	return m.Row*g.size + m.Col
+}
+
+// GetToken returns the token at the given space.
+func (g *Game) GetToken(move Move) string {
+	return g.board.GetToken(move.Row, move.Col)
+}
+
+// PlayerToMove returns the index of the player to move, 0 for X and 1 for O.
+func (g *Game) PlayerToMove() int {
+	return g.toMove
+}
+
+// Moves returns the open spaces within reach of the stones on the board,
+// strongest first and no more than candidateLimit of them. On an empty board
+// the only move considered is the centre.
+func (g *Game) Moves() []Move {
+	if over, _ := g.Result(); over {
+		return nil
+	}
+	if len(g.history) == 0 {
+		return []Move{{g.size / 2, g.size / 2}}
+	}
+
+	type candidate struct {
+		move     Move
+		priority int
+	}
+	var candidates []candidate
+	for r := 0; r < g.size; r++ {
+		for c := 0; c < g.size; c++ {
+			move := Move{r, c}
+			if g.near[g.index(move)] > 0 && g.GetToken(move) == " " {
+				candidates = append(candidates, candidate{move, g.priority(move)})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].priority > candidates[j].priority
+	})
+
+	moves := make([]Move, 0, min(len(candidates), candidateLimit))
+	for _, c := range candidates[:cap(moves)] {
+		moves = append(moves, c.move)
+	}
+	return moves
+}
+
+// priority estimates how strong a move is for the player to move, by what it
+// adds to their windows and what it takes away from the opponent's. Making
+// five counts for more than stopping the opponent's.
+func (g *Game) priority(move Move) int {
+	me, opponent := g.toMove, 1-g.toMove
+
+	attack, defence := 0, 0
+	for _, w := range g.windowsThrough[g.index(move)] {
+		if g.counts[w][opponent] == 0 {
+			attack += windowWeights[g.counts[w][me]+1]
+		}
+		if g.counts[w][me] == 0 {
+			defence += windowWeights[g.counts[w][opponent]+1]
+		}
+	}
+	return 2*attack + defence
+}
+
+// Play places the stone of the player to move. The space must be open.
+func (g *Game) Play(move Move) {
+	g.board.PlaceToken(move.Row, move.Col, minmax.Tokens[g.toMove])
+	g.history = append(g.history, move)
+	g.update(move, g.toMove, 1)
+
+	// Only lines through the new stone can have been completed
+	if g.board.CheckWinAt(move.Row, move.Col) {
+		g.winner = g.toMove
+	}
+	g.toMove = 1 - g.toMove
+}
+
+// Undo takes back the last move.
+func (g *Game) Undo(move Move) {
+	g.toMove = 1 - g.toMove
+	g.board.RemoveToken(move.Row, move.Col)
+	g.history = g.history[:len(g.history)-1]
+	g.update(move, g.toMove, -1)
+	g.winner = -1
+}
+
+// update adds delta stones of the player at the move to the windows through
+// it and to the spaces within reach of it.
+func (g *Game) update(move Move, player int, delta int) {
+	for _, w := range g.windowsThrough[g.index(move)] {
+		g.score[0] -= g.windowScore(w, 0)
+		g.score[1] -= g.windowScore(w, 1)
+		g.counts[w][player] += delta
+		g.score[0] += g.windowScore(w, 0)
+		g.score[1] += g.windowScore(w, 1)
+	}
+
+	for r := move.Row - reach; r <= move.Row+reach; r++ {
+		for c := move.Col - reach; c <= move.Col+reach; c++ {
+			if g.inBounds(Move{r, c}) {
+				g.near[g.index(Move{r, c})] += delta
+			}
+		}
+	}
+}
+
+// windowScore is the weight of the window for the player, if the opponent
+// has no stones in it.
+func (g *Game) windowScore(w int, player int) int {
+	if g.counts[w][1-player] > 0 {
+		return 0
+	}
+	return windowWeights[g.counts[w][player]]
+}
+
+// Outcome reports whether the game is over and, if so, the result for the
+// player to move.
+func (g *Game) Outcome() (bool, minmax.Outcome) {
+	over, winner := g.Result()
+	return minmax.OutcomeFor(over, winner, g.toMove)
+}
+
+// Result reports whether the game is over and the index of the winner, or
+// -1 for a draw.
+func (g *Game) Result() (bool, int) {
+	if g.winner != -1 {
+		return true, g.winner
+	}
+	return len(g.history) == g.size*g.size, -1
+}
+
+// windowWeights scores a window by the number of stones a player has in it.
+// Four is a threat the opponent has to answer, and an open four, with a free
+// space at both ends, is two of them.
+var windowWeights = [WinLength + 1]int{0, 1, 8, 64, 512, 4096}
+
+// Evaluate scores the position for the player to move by the windows that
+// only one of the players has stones in. Having the move is worth a lot when
+// both players have threats, so the player to move gets their windows
+// counted double. The score is capped to stay clear of the scores of won games.
+func (g *Game) Evaluate() int {
+	limit := minmax.WinScore / 2
+	return max(-limit, min(limit, 2*g.score[g.toMove]-g.score[1-g.toMove]))
+}
+
+// PlayAIMove plays the move minmax.PlayWithin finds for the AI difficulty,
+// which gains a ply every three levels. A time budget searches up to every
+// space of the board.
+func (g *Game) PlayAIMove(ctx context.Context, difficulty int, budget time.Duration) error {
+	return minmax.PlayWithin(ctx, g, minmax.AIDepth(difficulty, 1, 3), budget, g.size*g.size)
+}
+
+// MoveFormat describes the move input.
+func (g *Game) MoveFormat() string {
+	return "row,col"
+}
+
+// ParseMove parses a move string in the format "row,col". Returns an error if
+// the move is not in the correct format or is off the board.
+func (g *Game) ParseMove(input string) (Move, error) {
+	var move Move
+	_, err := fmt.Sscanf(strings.TrimSpace(input), "%d,%d", &move.Row, &move.Col)
+	if err != nil {
+		return Move{}, err
+	}
+
+	if !g.inBounds(move) {
+		return Move{}, fmt.Errorf("invalid move")
+	}
+	return move, nil
+}
+
+// PlayMove parses a move and plays it for the player to move.
+func (g *Game) PlayMove(input string) error {
+	move, err := g.ParseMove(input)
+	if err != nil {
+		return err
+	}
+	if over, _ := g.Result(); over || g.GetToken(move) != " " {
+		return fmt.Errorf("That space is already occupied. Please try again.")
+	}
+	g.Play(move)
+	return nil
+}
+
+// PrintBoard prints the board.
+func (g *Game) PrintBoard() {
+	g.board.PrintBoard()
+}
@@ -0,0 +1,133 @@
+package gomoku
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		size    int
+		windows int
+	}{
+		{15, 572},
+		{19, 1020},
+	}
+	for _, tt := range tests {
+		if got := len(NewGame(tt.size, false).windows); got != tt.windows {
+			t.Errorf("%dx%d: expected %d windows, got %d", tt.size, tt.size, tt.windows, got)
+		}
+	}
+}
+
+// playRow plays X on row 7 at the given columns, with O answering on row 0.
+func playRow(g *Game, cols ...int) {
+	for i, col := range cols {
+		g.Play(Move{7, col})
+		if over, _ := g.Result(); !over {
+			g.Play(Move{0, 2 * i})
+		}
+	}
+}
+
+func TestOverline(t *testing.T) {
+	tests := []struct {
+		name     string
+		exact    bool
+		expected bool
+	}{
+		{"Freestyle", false, true},
+		{"Standard", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Filling the gap at 7,5 joins the stones into six in a row
+			g := NewGame(15, tt.exact)
+			playRow(g, 2, 3, 4, 6, 7, 5)
+
+			if over, winner := g.Result(); over != tt.expected || (over && winner != 0) {
+				t.Errorf("expected game over to be %v, got %v with winner %d", tt.expected, over, winner)
+			}
+		})
+	}
+}
+
+func TestExactFiveWins(t *testing.T) {
+	g := NewGame(15, true)
+	playRow(g, 2, 3, 4, 5, 6)
+
+	if over, winner := g.Result(); !over || winner != 0 {
+		t.Fatalf("expected X to win, got %v %d", over, winner)
+	}
+
+	g.Undo(Move{7, 6})
+	if over, _ := g.Result(); over {
+		t.Error("undo should take back the win")
+	}
+}
+
+func TestMovesNearStones(t *testing.T) {
+	g := NewGame(15, false)
+	if moves := g.Moves(); len(moves) != 1 || moves[0] != (Move{7, 7}) {
+		t.Fatalf("expected the centre on an empty board, got %v", moves)
+	}
+
+	g.Play(Move{7, 7})
+	moves := g.Moves()
+	if len(moves) != candidateLimit {
+		t.Fatalf("expected %d moves, got %d", candidateLimit, len(moves))
+	}
+	for _, move := range moves {
+		if max(abs(move.Row-7), abs(move.Col-7)) > reach {
+			t.Errorf("expected moves within %d of the stone, got %v", reach, move)
+		}
+	}
+}
+
+func TestAIBlocksFour(t *testing.T) {
+	g := NewGame(15, false)
+	g.Play(Move{7, 7})
+	g.Play(Move{3, 3}) // O
+	g.Play(Move{7, 8})
+	g.Play(Move{3, 5}) // O
+	g.Play(Move{7, 9})
+	g.Play(Move{12, 12}) // O
+	g.Play(Move{7, 10})
+
+	// X has four in a row open at both ends, so O can't stop it, but must
+	// still block one end rather than let X win at once
+	if err := g.PlayAIMove(context.Background(), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	last := g.history[len(g.history)-1]
+	if last != (Move{7, 6}) && last != (Move{7, 11}) {
+		t.Errorf("expected O to block the four, got %v", last)
+	}
+}
+
+func TestAITakesWin(t *testing.T) {
+	g := NewGame(15, false)
+	g.Play(Move{7, 7})
+	g.Play(Move{8, 7}) // O
+	g.Play(Move{7, 8})
+	g.Play(Move{8, 8}) // O
+	g.Play(Move{7, 9})
+	g.Play(Move{8, 9}) // O
+	g.Play(Move{7, 10})
+	g.Play(Move{8, 10}) // O
+
+	// Both sides have four, X to move wins instead of blocking
+	if err := g.PlayAIMove(context.Background(), 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	if over, winner := g.Result(); !over || winner != 0 {
+		t.Errorf("expected X to win, got %v %d", over, winner)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,59 @@
+// Package gametest holds the games and checks shared by the tests of the
+// packages that learn from played games.
+package gametest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/game"
+)
+
+// CornerGame is a game X wins after opening in the top left corner.
+var CornerGame = []game.Move{
+	{Row: 0, Col: 0, Token: "X"},
+	{Row: 0, Col: 1, Token: "O"},
+	{Row: 1, Col: 1, Token: "X"},
+	{Row: 0, Col: 2, Token: "O"},
+	{Row: 2, Col: 2, Token: "X"},
+}
+
+// Replay returns the classic board after the first plies of the game.
+func Replay(history []game.Move, plies int) *board.Board {
+	b := board.NewBoard()
+	for _, move := range history[:plies] {
+		b.PlaceToken(move.Row, move.Col, move.Token)
+	}
+	return b
+}
+
+// ReadsBack writes w, reads it back with read and checks that what was read
+// writes the same, and that read rejects a file of another kind. It returns
+// what was read.
+func ReadsBack[T io.WriterTo](t *testing.T, w io.WriterTo, read func(io.Reader) (T, error)) T {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var again bytes.Buffer
+	if _, err := got.WriteTo(&again); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != buf.String() {
+		t.Errorf("expected the file to read back unchanged, got\n%s\nwant\n%s", again.String(), buf.String())
+	}
+
+	if _, err := read(bytes.NewBufferString("not this kind of file\n")); err == nil {
+		t.Error("expected an error for a file of another kind")
+	}
+	return got
+}
@@ -0,0 +1,199 @@
+// Package learn is an AI player that learns to play by temporal-difference
+// learning instead of searching. It keeps a table with the value of every
+// position it has seen for the player who moved into it, between 0 for a
+// certain loss and 1 for a certain win, and plays the move leading to the
+// position with the highest value.
+package learn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/game"
+)
+
+// magic starts every agent file, followed by the format version.
+const (
+	magic   = "TTTAGENT"
+	version = 1
+)
+
+// Unknown is the value of a position the agent hasn't learned anything about,
+// the same as a draw.
+const Unknown = 0.5
+
+// Agent learns the values of the positions of games played with Rules.
+type Agent struct {
+	Rules board.Rules
+
+	// LearningRate is how far a value moves towards the value of the
+	// position that followed it, from 0 for not at all to 1 for all the way.
+	LearningRate float64
+
+	// Exploration is the chance of a random move in place of the best one
+	// while training, so the agent keeps trying moves it doesn't rate yet.
+	Exploration float64
+
+	// values maps the board.CanonicalKey of each position to its learned
+	// value for the player who moved into it.
+	values map[string]float64
+}
+
+// NewAgent returns an agent for the rules that hasn't learned anything yet.
+func NewAgent(rules board.Rules, learningRate float64, exploration float64) *Agent {
+	return &Agent{Rules: rules, LearningRate: learningRate, Exploration: exploration, values: make(map[string]float64)}
+}
+
+// Len returns the number of canonical positions the agent has a value for.
+func (a *Agent) Len() int {
+	return len(a.values)
+}
+
+// Value returns the value of the position for the player holding mover, who
+// just moved into it. A won game is worth 1 and a tie Unknown, like any
+// position that hasn't been learned.
+func (a *Agent) Value(position board.Board, mover string) float64 {
+	if position.CheckWinForPlayer(mover) {
+		return 1
+	}
+	if value, ok := a.values[position.CanonicalKey(mover)]; ok {
+		return value
+	}
+	return Unknown
+}
+
+// BestMove returns the move leading to the position with the highest value
+// for the player holding playerToken, picking at random between equal ones.
+// It returns false if there is no move, or the board isn't played with the
+// agent's rules.
+func (a *Agent) BestMove(position board.Board, playerToken string) (int, int, bool) {
+	moves := a.moves(position)
+	if len(moves) == 0 {
+		return -1, -1, false
+	}
+
+	position = *position.Clone()
+	var best [][2]int
+	bestValue := -1.0
+	for _, move := range moves {
+		position.PlaceToken(move[0], move[1], playerToken)
+		value := a.Value(position, playerToken)
+		position.RemoveToken(move[0], move[1])
+
+		switch {
+		case value > bestValue:
+			best, bestValue = [][2]int{move}, value
+		case value == bestValue:
+			best = append(best, move)
+		}
+	}
+	move := best[rand.Intn(len(best))]
+	return move[0], move[1], true
+}
+
+// TrainingMove is the move played while training: a random one with the
+// chance of Exploration, and the best one otherwise.
+func (a *Agent) TrainingMove(position board.Board, playerToken string) (int, int, bool) {
+	if moves := a.moves(position); len(moves) > 0 && rand.Float64() < a.Exploration {
+		move := moves[rand.Intn(len(moves))]
+		return move[0], move[1], true
+	}
+	return a.BestMove(position, playerToken)
+}
+
+// moves returns the spaces a token can be placed on, or none if the board
+// isn't played with the agent's rules.
+func (a *Agent) moves(position board.Board) [][2]int {
+	if position.Rules() != a.Rules {
+		return nil
+	}
+	var moves [][2]int
+	for row := 0; row < position.Rows(); row++ {
+		for col := 0; col < position.Cols(); col++ {
+			if position.CanPlace(row, col) {
+				moves = append(moves, [2]int{row, col})
+			}
+		}
+	}
+	return moves
+}
+
+// Learn replays a game from an empty board and moves the value of each
+// position a player moved into towards the value of the next position they
+// moved into, and the last one towards the result of the game. The winner is
+// the token of the player who won, or empty for a draw.
+func (a *Agent) Learn(history []game.Move, winner string) {
+	// The positions each player moved into, in order
+	reached := make(map[string][]string)
+	position := board.NewBoardWithRules(a.Rules)
+	for _, move := range history {
+		position.PlaceToken(move.Row, move.Col, move.Token)
+		reached[move.Token] = append(reached[move.Token], position.CanonicalKey(move.Token))
+	}
+
+	for token, keys := range reached {
+		result := 0.0
+		switch winner {
+		case "":
+			result = Unknown
+		case token:
+			result = 1
+		}
+
+		for i, key := range keys {
+			target := result
+			if i+1 < len(keys) {
+				target = a.value(keys[i+1])
+			}
+			value := a.value(key)
+			a.values[key] = value + a.LearningRate*(target-value)
+		}
+	}
+}
+
+// value returns the learned value of the position with the key.
+func (a *Agent) value(key string) float64 {
+	if value, ok := a.values[key]; ok {
+		return value
+	}
+	return Unknown
+}
+
+// WriteTo writes the learned values as text: a board.Header, and then a line
+// for each position, sorted, with its quoted key and its value.
+func (a *Agent) WriteTo(w io.Writer) (int64, error) {
+	var lines []string
+	for key, value := range a.values {
+		lines = append(lines, fmt.Sprintf("%q %g\n", key, value))
+	}
+	sort.Strings(lines)
+
+	n, err := io.WriteString(w, board.Header(magic, version, a.Rules)+strings.Join(lines, ""))
+	return int64(n), err
+}
+
+// Read reads the values written by WriteTo into a new agent with the
+// learning rate and exploration.
+func Read(r io.Reader, learningRate float64, exploration float64) (*Agent, error) {
+	scanner := bufio.NewScanner(r)
+	rules, err := board.ReadHeader(scanner, magic, version)
+	if err != nil {
+		return nil, err
+	}
+
+	a := NewAgent(rules, learningRate, exploration)
+	for line := 2; scanner.Scan(); line++ {
+		var key string
+		var value float64
+		if _, err := fmt.Sscanf(scanner.Text(), "%q %g", &key, &value); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		a.values[key] = value
+	}
+	return a, scanner.Err()
+}
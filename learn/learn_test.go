@@ -0,0 +1,71 @@
+package learn
+
+import (
+	"io"
+	"testing"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/internal/gametest"
+)
+
+func TestLearn(t *testing.T) {
+	a := NewAgent(board.ClassicRules, 0.5, 0)
+	a.Learn(gametest.CornerGame, "X")
+
+	// Only the last positions are next to the result
+	if v := a.Value(*gametest.Replay(gametest.CornerGame, 4), "O"); v != 0.25 {
+		t.Errorf("expected O's last position to drop to 0.25, got %v", v)
+	}
+	if v := a.Value(*gametest.Replay(gametest.CornerGame, 3), "X"); v != Unknown {
+		t.Errorf("expected X's second position to stay unknown, got %v", v)
+	}
+	if v := a.Value(*gametest.Replay(gametest.CornerGame, 5), "X"); v != 1 {
+		t.Errorf("expected the won position to be worth 1, got %v", v)
+	}
+
+	// The result works its way back to the opening over more games
+	for i := 0; i < 50; i++ {
+		a.Learn(gametest.CornerGame, "X")
+	}
+	if v := a.Value(*gametest.Replay(gametest.CornerGame, 1), "X"); v < 0.9 {
+		t.Errorf("expected the opening to approach 1, got %v", v)
+	}
+
+	// The other corners are the same opening turned around
+	corner := board.NewBoard()
+	corner.PlaceToken(2, 0, "X")
+	if a.Value(*corner, "X") != a.Value(*gametest.Replay(gametest.CornerGame, 1), "X") {
+		t.Error("expected symmetric positions to share their value")
+	}
+}
+
+func TestBestMove(t *testing.T) {
+	a := NewAgent(board.ClassicRules, 0.5, 0)
+	b := gametest.Replay(gametest.CornerGame, 4)
+	if row, col, ok := a.BestMove(*b, "X"); !ok || row != 2 || col != 2 {
+		t.Errorf("expected X to win at 2,2, got %d,%d", row, col)
+	}
+
+	// O learns that leaving the diagonal open loses
+	for i := 0; i < 10; i++ {
+		a.Learn(gametest.CornerGame, "X")
+	}
+	b = gametest.Replay(gametest.CornerGame, 3)
+	if row, col, ok := a.BestMove(*b, "O"); !ok || (row == 0 && col == 2) {
+		t.Errorf("expected O to avoid 0,2, got %d,%d", row, col)
+	}
+
+	if _, _, ok := a.BestMove(*board.NewBoardWithRules(board.ConnectFourRules), "X"); ok {
+		t.Error("expected no move on a board with other rules")
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	a := NewAgent(board.ClassicRules, 0.3, 0)
+	a.Learn(gametest.CornerGame, "")
+
+	read := gametest.ReadsBack(t, a, func(r io.Reader) (*Agent, error) { return Read(r, 0.1, 0.2) })
+	if read.Rules != a.Rules || read.Len() != a.Len() || read.LearningRate != 0.1 || read.Exploration != 0.2 {
+		t.Errorf("expected %v with %d positions, got %v with %d", a.Rules, a.Len(), read.Rules, read.Len())
+	}
+}
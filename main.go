@@ -2,12 +2,24 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/book"
 	"github.com/jackmcdermo/tic-tac-toe-/game"
+	"github.com/jackmcdermo/tic-tac-toe-/learn"
 	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+	"github.com/jackmcdermo/tic-tac-toe-/tablebase"
 )
 
 type channelData struct {
@@ -18,12 +30,92 @@ type channelData struct {
 const (
 	NEW_GAME_PROMPT = "Enter '1' to play against a friend, '2' to play against the AI,  or '3' for two AI players to square off! Press 'q' to quit."
 	USE_RANDOM_AI   = false
+	USE_TABLEBASE   = true // look up the moves of the hardest AI level instead of searching
+	BOARD_PROMPT    = "Enter the board as rows,cols,in-a-row, adding ',gravity' to drop tokens into columns, ',exact' if longer lines don't win, ',torus' for lines that wrap around the edges and the rows of a layout with '#' for blocked spaces (e.g. 6,7,4,gravity for Connect Four or 3,3,3,..#/.../#.. for two blocked corners): "
+
+	// LARGE_BOARD_MOVE_TIME is the time an AI player spends on a move per AI
+	// level on boards too large to search to the depth of the level.
+	LARGE_BOARD_MOVE_TIME = 200 * time.Millisecond
+)
+
+// misereRules is whether new classic games are played with misère rules.
+var misereRules = false
+
+// wildRules is whether new classic games are played with Wild rules.
+var wildRules = false
+
+// numericalRules is whether new classic games are Numerical tic-tac-toe,
+// which is always played on the classic board.
+var numericalRules = false
+
+// orderChaosRules is whether new classic games are Order and Chaos, which
+// is always played on the 6x6 board.
+var orderChaosRules = false
+
+// playerCount is the number of players in new classic games, two or three.
+var playerCount = 2
+
+// playerTokens are the tokens of the players of classic games, in turn order.
+var playerTokens = []string{"X", "O", "△"}
+
+// threePlayerRules is the board a game switches to when a third player
+// joins a game on the classic board, which is too small for three.
+var threePlayerRules = board.Rules{Rows: 5, Cols: 5, WinLength: 3}
+
+// boardRules are the rules of the board new classic games are played on.
+var boardRules = board.ClassicRules
+
+// boardLayout is the layout of the board new classic games start from, in the
+// notation of board.ParseLayout, or empty for an empty board.
+var boardLayout = ""
+
+// choosingBoard is whether the next input is the board for new games.
+var choosingBoard = false
+
+// moveTimeLimit is the time an AI player may spend on a move. When it is zero
+// the AI plays the minmax.Profile of its difficulty level instead.
+var moveTimeLimit time.Duration
+
+// learnedAgent is the agent loaded with -agent, which AI players can play
+// with in place of the search, or nil.
+var learnedAgent *learn.Agent
+
+// openingBook is the book loaded with -book, which AI players play their
+// first moves from and which learns from the games played, or nil.
+var openingBook *book.Book
+
+// bookFile is the file the opening book is written back to after each game.
+var bookFile string
+
+// cancelAIMove stops the AI search that is running, if any. The input reader
+// calls it when the user quits so they don't have to wait for the AI.
+var (
+	cancelAIMoveMu sync.Mutex
+	cancelAIMove   context.CancelFunc
 )
 
 func main() {
 
+	flag.DurationVar(&moveTimeLimit, "movetime", 0, "Time limit per AI move, e.g. 500ms (overrides the AI level)")
+	agentFile := flag.String("agent", "", "A learned agent file, trained by the simulation, for AI players to play with")
+	flag.StringVar(&bookFile, "book", "", "An opening book file for AI players to play their first moves from and to learn into, none if empty")
+	flag.Parse()
+
+	if *agentFile != "" {
+		if err := loadAgent(*agentFile); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if bookFile != "" {
+		if err := loadBook(bookFile); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
 	fmt.Println("Welcome to Tic-Tac-Toe!")
-	fmt.Println(NEW_GAME_PROMPT)
+	printNewGamePrompt()
 
 	var gameInstance *game.Game = nil
 
@@ -34,10 +126,22 @@ func main() {
 		reader := bufio.NewReader(os.Stdin)
 		for {
 			text, _ := reader.ReadString('\n')
+			if text == "q\n" {
+				stopAIMove()
+			}
 			inputChannel <- channelData{gameInstance, text}
 		}
 	}()
 	for channelData := range inputChannel {
+		// A variant game handles its own input until it is over
+		if session != nil {
+			handleVariantInput(channelData.input)
+			continue
+		}
+
+		// The reader may have sent the line before the previous one was
+		// handled, so always use the latest game instance.
+		channelData.gameInstance = gameInstance
 		gameInstance = handleUserInput(channelData)
 	}
 }
@@ -51,12 +155,10 @@ func handleUserInput(data channelData) *game.Game {
 		return handleNewGameInput(data.input)
 	}
 
-	if data.gameInstance != nil && playerNeedsAILevel(data.gameInstance.Player1) {
-		return handleSetAILevel(data.gameInstance, &data.gameInstance.Player1, data.input)
-	}
-
-	if data.gameInstance != nil && playerNeedsAILevel(data.gameInstance.Player2) {
-		return handleSetAILevel(data.gameInstance, &data.gameInstance.Player2, data.input)
+	for i, player := range data.gameInstance.Players {
+		if playerNeedsAILevel(player) {
+			return handleSetAILevel(data.gameInstance, &data.gameInstance.Players[i], data.input)
+		}
 	}
 
 	return handlePlayerMove(data.gameInstance, data.input)
@@ -68,45 +170,147 @@ func playerNeedsAILevel(player game.Player) bool {
 
 // parseMove parses a move string in the format "row, col" and returns the row and column
 // values. Returns an error if the move is not in the correct format or if the row or column
-// values are out of range.
-func parseMove(move string) (int, int, error) {
+// values are out of range. With gravity the move is just a column, and the row is the one
+// the token lands on.
+func parseMove(b *board.Board, move string) (int, int, error) {
 	var row, col int
+	if b.Rules().Gravity {
+		if _, err := fmt.Sscanf(move, "%d", &col); err != nil {
+			return 0, 0, err
+		}
+		if col < 0 || col >= b.Cols() {
+			return 0, 0, fmt.Errorf("invalid move")
+		}
+		if row = b.DropRow(col); row == -1 {
+			return 0, 0, fmt.Errorf("That column is full.")
+		}
+		return row, col, nil
+	}
+
 	_, err := fmt.Sscanf(move, "%d,%d", &row, &col)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	if row < 0 || row > 2 || col < 0 || col > 2 {
+	if !b.InBounds(row, col) {
 		return 0, 0, fmt.Errorf("invalid move")
 	}
 	return row, col, nil
 }
 
-// initGame initializes a new game instance with the given player configuration.
-func initGame(humanPlayerCount int, randomAI bool, p1ai int, p2ai int) *game.Game {
+// parseTokenMove parses a move like parseMove, followed by the token to place,
+// e.g. "1,1,O" in Wild or "1,1,7" in Numerical games. Whether the player may
+// place the token is left to the game.
+func parseTokenMove(b *board.Board, move string) (int, int, string, error) {
+	i := strings.LastIndex(move, ",")
+	token := ""
+	if i != -1 {
+		token = strings.ToUpper(strings.TrimSpace(move[i+1:]))
+	}
+	if token == "" {
+		return 0, 0, "", fmt.Errorf("Please add the token to place.")
+	}
 
-	player1 := game.NewPlayer("X", false, 0, "Player 1 (X)")
-	player2 := game.NewPlayer("O", false, 0, "Player 2 (O)")
+	row, col, err := parseMove(b, move[:i])
+	return row, col, token, err
+}
 
-	if humanPlayerCount < 2 {
-		name := fmt.Sprintf("Player 2 (O, ai: %d)", p1ai)
-		player2 = game.NewPlayer("O", true, p1ai, name)
+// parseBoard parses a board in the format "rows,cols,in-a-row", optionally
+// followed by ",gravity", ",exact", ",torus" and a layout in the notation of
+// board.ParseLayout.
+func parseBoard(input string) (board.Rules, string, error) {
+	var rules board.Rules
+	layout := ""
+	fields := strings.Split(strings.TrimSpace(input), ",")
+	if len(fields) < 3 {
+		return rules, "", fmt.Errorf("Please enter the board as rows,cols,in-a-row.")
 	}
 
-	if humanPlayerCount < 1 {
-		name := fmt.Sprintf("Player 1 (X, ai: %d)", p1ai)
-		player1 = game.NewPlayer("X", true, p2ai, name)
+	for _, option := range fields[3:] {
+		switch option = strings.TrimSpace(option); {
+		case option == "gravity":
+			rules.Gravity = true
+		case option == "exact":
+			rules.Exact = true
+		case option == "torus":
+			rules.Torus = true
+		case strings.ContainsAny(option, "./#"):
+			layout = option
+		default:
+			return rules, "", fmt.Errorf("Unknown board option %q, expected gravity, exact, torus or a layout.", option)
+		}
 	}
 
-	gameInstance := game.NewGame([]game.Player{player1, player2}, randomAI)
+	sizes := []*int{&rules.Rows, &rules.Cols, &rules.WinLength}
+	for i, field := range fields[:3] {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return rules, "", fmt.Errorf("Please enter the board as rows,cols,in-a-row.")
+		}
+		*sizes[i] = n
+	}
+	if err := rules.Validate(); err != nil {
+		return rules, "", err
+	}
+	if layout != "" {
+		if _, err := board.ParseLayout(rules, layout, playerTokens[:playerCount]); err != nil {
+			return rules, "", err
+		}
+	}
+	return rules, layout, nil
+}
+
+// newBoard returns the board new classic games are played on.
+func newBoard() *board.Board {
+	if boardLayout == "" {
+		return board.NewBoardWithRules(boardRules)
+	}
+	// The layout was checked when it was entered
+	b, _ := board.ParseLayout(boardRules, boardLayout, playerTokens[:playerCount])
+	return b
+}
+
+// initGame initializes a new game instance with the given player configuration.
+func initGame(humanPlayerCount int, randomAI bool) *game.Game {
+
+	gameInstance := game.NewGame(newPlayers(playerCount, humanPlayerCount), randomAI)
+	switch {
+	case orderChaosRules:
+		gameInstance.Board = board.NewBoardWithRules(board.OrderChaosRules)
+	case !numericalRules:
+		gameInstance.Board = newBoard()
+	}
+	gameInstance.Misere = misereRules
+	gameInstance.Wild = wildRules
+	gameInstance.Numerical = numericalRules
+	gameInstance.OrderChaos = orderChaosRules
 	gameInstance.InitGame()
+	return promptAILevel(gameInstance)
+}
 
-	if playerNeedsAILevel(player1) {
-		printAILevelPrompt(player1.Name)
-		return gameInstance
-	} else if playerNeedsAILevel(player2) {
-		printAILevelPrompt(player2.Name)
-		return gameInstance
+// newPlayers returns count players, the first humanPlayerCount of them human
+// and the rest played by the AI, whose levels are still to be set.
+func newPlayers(count int, humanPlayerCount int) []game.Player {
+	players := make([]game.Player, count)
+	for i := range players {
+		token := playerTokens[i]
+		if i < humanPlayerCount {
+			players[i] = game.NewPlayer(token, false, 0, fmt.Sprintf("Player %d (%s)", i+1, token))
+		} else {
+			players[i] = game.NewPlayer(token, true, -1, fmt.Sprintf("Player %d (%s, ai)", i+1, token))
+		}
+	}
+	return players
+}
+
+// promptAILevel asks for the level of the first AI player without one, or
+// starts the game once all are set.
+func promptAILevel(gameInstance *game.Game) *game.Game {
+	for _, player := range gameInstance.Players {
+		if playerNeedsAILevel(player) {
+			printAILevelPrompt(player.Name, learnedAgent != nil)
+			return gameInstance
+		}
 	}
 	return startGame(gameInstance)
 }
@@ -114,6 +318,19 @@ func initGame(humanPlayerCount int, randomAI bool, p1ai int, p2ai int) *game.Gam
 func startGame(gameInstance *game.Game) *game.Game {
 	player := gameInstance.NextMovePlayer()
 	fmt.Printf("%s won the coin toss. so they go first!\n", player.Name)
+	if gameInstance.Misere {
+		fmt.Printf("Misère rules: whoever completes %d in a row loses!\n", gameInstance.Board.Rules().WinLength)
+	}
+	if gameInstance.Numerical {
+		fmt.Printf("Numerical rules: %s places the odd numbers, %s the even ones, and a line adding up to %d wins!\n", gameInstance.Players[0].Name, gameInstance.Players[1].Name, game.NumericalSum)
+	} else if gameInstance.Wild {
+		fmt.Println("Wild rules: place X or O on every move, and a line counts for whoever completes it!")
+	} else if gameInstance.OrderChaos {
+		fmt.Printf("Order and Chaos: both players place X or O. %s plays Order and wins with exactly five X or five O in a row, %s plays Chaos and wins by filling the board without one!\n", gameInstance.Players[0].Name, gameInstance.Players[1].Name)
+	}
+	if slices.ContainsFunc(gameInstance.Players, func(p game.Player) bool { return !p.IsAI }) {
+		fmt.Println("Enter 'hint' for a suggested move or 'analyze' to see how every move plays out.")
+	}
 	gameInstance.Board.PrintBoard()
 	gameInstance.PrintMovePrompt()
 	return gameInstance
@@ -122,36 +339,197 @@ func startGame(gameInstance *game.Game) *game.Game {
 // handleNewGameInput handles the user's input when starting a new game.
 func handleNewGameInput(input string) *game.Game {
 
+	if choosingBoard && input != "q\n" {
+		handleBoardInput(input)
+		return nil
+	}
+
 	switch input {
-	case "1\n": // Two human players
-		return initGame(2, USE_RANDOM_AI, 0, 0)
+	case "1\n": // Human players only
+		return initGame(playerCount, USE_RANDOM_AI)
 	case "2\n": // Player vs AI
-		return initGame(1, USE_RANDOM_AI, 0, -1)
+		return initGame(1, USE_RANDOM_AI)
 	case "3\n": // AI vs AI
-		return initGame(0, USE_RANDOM_AI, -1, -1)
+		return initGame(0, USE_RANDOM_AI)
+	case "m\n": // Toggle misère rules
+		misereRules = !misereRules
+		if misereRules {
+			playerCount = 2
+			orderChaosRules = false
+		}
+		printNewGamePrompt()
+		return nil
+	case "w\n": // Toggle Wild rules
+		wildRules = !wildRules
+		if wildRules {
+			playerCount = 2
+			orderChaosRules = false
+		}
+		printNewGamePrompt()
+		return nil
+	case "f\n": // Toggle Numerical rules
+		numericalRules = !numericalRules
+		if numericalRules {
+			playerCount = 2
+			orderChaosRules = false
+		}
+		printNewGamePrompt()
+		return nil
+	case "o\n": // Toggle Order and Chaos
+		orderChaosRules = !orderChaosRules
+		if orderChaosRules {
+			playerCount = 2
+			misereRules, wildRules, numericalRules = false, false, false
+		}
+		printNewGamePrompt()
+		return nil
+	case "p\n": // Switch between two and three players
+		togglePlayerCount()
+		printNewGamePrompt()
+		return nil
+	case "b\n": // Change the board
+		choosingBoard = true
+		fmt.Print(BOARD_PROMPT)
+		return nil
 	case "q\n": // Quit
 		fmt.Println("Thanks for playing!")
 		os.Exit(0)
 	default:
-		fmt.Printf("Invalid input. %s\n", NEW_GAME_PROMPT)
+		if v, ok := findVariant(input); ok {
+			startVariant(v)
+			return nil
+		}
+		fmt.Print("Invalid input. ")
+		printNewGamePrompt()
 	}
 	return nil
 }
 
+// togglePlayerCount switches new classic games between two and three players.
+// A third player needs more room than the classic board, so a game on it
+// moves to the threePlayerRules board. Misère, Wild, Numerical and Order and
+// Chaos rules are only for two players and are turned off.
+func togglePlayerCount() {
+	if playerCount == 3 {
+		playerCount = 2
+		// The third player's tokens don't belong in a two player game
+		if _, err := board.ParseLayout(boardRules, boardLayout, playerTokens[:playerCount]); boardLayout != "" && err != nil {
+			boardLayout = ""
+		}
+		return
+	}
+
+	playerCount = 3
+	if boardRules == board.ClassicRules {
+		boardRules, boardLayout = threePlayerRules, ""
+	}
+	misereRules, wildRules, numericalRules, orderChaosRules = false, false, false, false
+}
+
+// handleBoardInput sets the board new games are played on.
+func handleBoardInput(input string) {
+	rules, layout, err := parseBoard(input)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Print(BOARD_PROMPT)
+		return
+	}
+	boardRules, boardLayout = rules, layout
+	choosingBoard = false
+	printNewGamePrompt()
+}
+
+// parseAILevel parses an AI level between 1 and 10 and returns it as an AI
+// difficulty between 0 and 9.
+func parseAILevel(input string) (int, error) {
+	level, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || level < 1 || level > 10 {
+		return 0, fmt.Errorf("Please enter a valid number between 1 and 10.")
+	}
+	return level - 1, nil
+}
+
 func handleSetAILevel(gameInstance *game.Game, player *game.Player, input string) *game.Game {
 
-	level, err := strconv.Atoi(input)
+	if learnedAgent != nil && strings.TrimSpace(input) == "l" {
+		if err := canPlayLearned(gameInstance); err != nil {
+			fmt.Println(err)
+			return gameInstance
+		}
+		player.Learned = true
+		player.AiPlayerDifficulty = 0
+		return promptAILevel(gameInstance)
+	}
+
+	difficulty, err := parseAILevel(input)
 	if err != nil {
-		fmt.Println("Please enter a valid number between 1 and 10.")
+		fmt.Println(err)
 		return gameInstance
 	}
+	player.AiPlayerDifficulty = difficulty
 
-	if level < 1 || level > 10 {
-		fmt.Println("Please enter a valid number between 1 and 10.")
-		return gameInstance
+	// Ask for the next AI level, or start the game once all are set
+	return promptAILevel(gameInstance)
+}
+
+// loadAgent reads the learned agent in the file.
+func loadAgent(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
 	}
-	player.AiPlayerDifficulty = level - 1
-	return gameInstance
+	defer f.Close()
+	learnedAgent, err = learn.Read(f, 0, 0)
+	return err
+}
+
+// loadBook reads the opening book in the file, or starts a new one for the
+// classic board if the file doesn't exist yet.
+func loadBook(file string) error {
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		openingBook = book.New(board.ClassicRules, 2)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	openingBook, err = book.Read(f)
+	return err
+}
+
+// saveBook writes the opening book back to its file.
+func saveBook() error {
+	f, err := os.Create(bookFile)
+	if err != nil {
+		return err
+	}
+	if _, err := openingBook.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// usesBook reports whether the game's opening is played from and recorded
+// into the opening book. The book replays games from an empty board of its
+// rules, with two players placing their own tokens.
+func usesBook(gameInstance *game.Game) bool {
+	return openingBook != nil && gameInstance.Board.Rules() == openingBook.Rules && !gameInstance.Board.HasBlocked() && gameInstance.Board.StartsEmpty() &&
+		len(gameInstance.Players) == 2 && !gameInstance.Misere && !gameInstance.PicksToken()
+}
+
+// canPlayLearned returns an error if the learned agent can't play the game,
+// which must be between two players placing their own tokens on the board
+// the agent was trained on.
+func canPlayLearned(gameInstance *game.Game) error {
+	if len(gameInstance.Players) != 2 || gameInstance.Misere || gameInstance.PicksToken() || gameInstance.Board.HasBlocked() {
+		return fmt.Errorf("The learned agent only plays two player games with standard rules. Please enter an AI level.")
+	}
+	if rules := gameInstance.Board.Rules(); rules != learnedAgent.Rules {
+		return fmt.Errorf("The learned agent was trained on %s, not %s. Please enter an AI level.", learnedAgent.Rules, rules)
+	}
+	return nil
 }
 
 // handlePlayerMove handles the user's move input. If the move is valid, the move is placed
@@ -168,38 +546,69 @@ func handlePlayerMove(gameInstance *game.Game, move string) *game.Game {
 	var row, col int
 	var err error
 	nextMovePlayer := gameInstance.NextMovePlayer()
+	token := nextMovePlayer.Token
 	// Have the AI player make a move if it is their turn
 	if nextMovePlayer.IsAI {
 		fmt.Println("AI player is making a move...")
-		if gameInstance.RandomAI {
-			row, col = minmax.GetBestMoveWithRandom(*gameInstance.Board, nextMovePlayer.AiPlayerDifficulty, nextMovePlayer.Token)
-		} else {
-			row, col = minmax.GetBestMove(*gameInstance.Board, nextMovePlayer.AiPlayerDifficulty, nextMovePlayer.Token)
+		ctx := startAIMove()
+		row, col, token, err = aiMove(ctx, gameInstance, nextMovePlayer)
+		stopAIMove()
+		if err != nil {
+			fmt.Println("AI move cancelled.")
+			return gameInstance
+		}
+		if !gameInstance.Board.InBounds(row, col) {
+			printGameOverMessage("The AI player has no move left, it's a tie!", gameInstance)
+			return nil
 		}
 		// Ortherwise, parse the user's move
 	} else {
-		row, col, err = parseMove(move)
+		switch move {
+		case "hint\n":
+			printHint(gameInstance)
+			return gameInstance
+		case "analyze\n":
+			printAnalysis(gameInstance)
+			return gameInstance
+		}
+
+		if gameInstance.PicksToken() {
+			row, col, token, err = parseTokenMove(gameInstance.Board, move)
+		} else {
+			row, col, err = parseMove(gameInstance.Board, move)
+		}
 		if err != nil {
 			fmt.Println(err)
 			gameInstance.PrintMovePrompt()
+			return gameInstance
 		}
 	}
 
 	// Place the user's move on the board
-	result := gameInstance.DoMove(row, col)
+	result := gameInstance.DoMoveWithToken(row, col, token)
 
 	switch result {
 
 	case game.ValidMove:
 		printNextMoveMessage(gameInstance, "")
 	case game.SpaceOccupied:
-		printNextMoveMessage(gameInstance, "That space is already occupied. Please try again.")
+		if gameInstance.Board.GetToken(row, col) == board.Blocked {
+			printNextMoveMessage(gameInstance, "That space is blocked. Please try again.")
+		} else {
+			printNextMoveMessage(gameInstance, "That space is already occupied. Please try again.")
+		}
+	case game.WrongToken:
+		printNextMoveMessage(gameInstance, "You can't place that token. Please try again.")
 	case game.XWin:
 		printGameOverMessage("Player 1 wins!", gameInstance)
 		return nil
 	case game.OWin:
 		printGameOverMessage("Player 2 wins!", gameInstance)
 		return nil
+	case game.Win:
+		winner, _ := gameInstance.Winner()
+		printGameOverMessage(fmt.Sprintf("%s wins!", winner.Name), gameInstance)
+		return nil
 	case game.Tie:
 		printGameOverMessage("It's a tie!", gameInstance)
 		return nil
@@ -208,6 +617,150 @@ func handlePlayerMove(gameInstance *game.Game, move string) *game.Game {
 	return gameInstance
 }
 
+// aiMove returns the move the AI plays for the player with the token to
+// place. Unless the hardest level can look it up in the tablebase, the first
+// moves come from the opening book if there is one, and a player of the
+// learned agent plays its moves. Otherwise it is searchMove's.
+func aiMove(ctx context.Context, gameInstance *game.Game, player game.Player) (int, int, string, error) {
+	if _, _, ok := tablebaseMove(gameInstance, player); !ok {
+		if usesBook(gameInstance) {
+			if row, col, ok := openingBook.Choose(*gameInstance.Board, player.Token); ok {
+				return row, col, player.Token, nil
+			}
+		}
+		if player.Learned {
+			if row, col, ok := learnedAgent.BestMove(*gameInstance.Board, player.Token); ok {
+				return row, col, player.Token, nil
+			}
+		}
+	}
+	return searchMove(ctx, gameInstance, player)
+}
+
+// tablebaseMove looks the move up in the tablebase for the hardest level,
+// and returns false for other levels or positions the tablebase doesn't hold.
+func tablebaseMove(gameInstance *game.Game, player game.Player) (int, int, bool) {
+	if !USE_TABLEBASE || len(gameInstance.Players) != 2 || gameInstance.Misere || gameInstance.PicksToken() || moveTimeLimit != 0 || player.AiPlayerDifficulty != 9 {
+		return -1, -1, false
+	}
+	return tablebase.Default().BestMove(*gameInstance.Board, player.Token)
+}
+
+// searchMove searches for the move the AI plays for the player at its level,
+// or looks it up in the tablebase at the hardest one, and returns it with
+// the token to place.
+func searchMove(ctx context.Context, gameInstance *game.Game, player game.Player) (int, int, string, error) {
+	if row, col, ok := tablebaseMove(gameInstance, player); ok {
+		return row, col, player.Token, nil
+	}
+
+	options := searchOptions(gameInstance)
+	budget := moveTimeLimit
+	if budget == 0 && (isLargeBoard(gameInstance.Board) || gameInstance.Numerical) {
+		budget = time.Duration(player.AiPlayerDifficulty+1) * LARGE_BOARD_MOVE_TIME
+	}
+
+	var move minmax.Move
+	var err error
+	if budget > 0 {
+		move, err = minmax.GetBestPlacementWithin(ctx, *gameInstance.Board, budget, player.Token, options)
+	} else {
+		move, _, err = minmax.GetPlacementWithProfile(ctx, *gameInstance.Board, minmax.ProfileFor(player.AiPlayerDifficulty), player.Token, options)
+	}
+	if move.Token == "" {
+		move.Token = player.Token
+	}
+	return move.Row, move.Col, move.Token, err
+}
+
+// isLargeBoard reports whether the board has more spaces than the classic
+// one, so that it can't be searched or solved to the end. Numerical games
+// have too many moves for that as well, as each space takes several numbers.
+func isLargeBoard(b *board.Board) bool {
+	return b.Rows()*b.Cols() > 9
+}
+
+// searchOptions returns the options for searching the game's board.
+func searchOptions(gameInstance *game.Game) minmax.Options {
+	return minmax.Options{
+		Random:     gameInstance.RandomAI,
+		Misere:     gameInstance.Misere,
+		Wild:       gameInstance.Wild,
+		Numerical:  gameInstance.Numerical,
+		OrderChaos: gameInstance.OrderChaos,
+		Opponents:  gameInstance.Opponents(gameInstance.NextMovePlayer()),
+		Evaluator:  minmax.DefaultEvaluator,
+	}
+}
+
+// printHint prints the move the hardest AI level would search for in the
+// human player's place, without the opening book or the learned agent.
+func printHint(gameInstance *game.Game) {
+	player := gameInstance.NextMovePlayer()
+	player.AiPlayerDifficulty = 9
+	row, col, token, _ := searchMove(context.Background(), gameInstance, player)
+	hint := fmt.Sprintf("%d,%d", row, col)
+	if gameInstance.Board.Rules().Gravity {
+		hint = fmt.Sprint(col)
+	}
+	if gameInstance.PicksToken() {
+		hint += "," + token
+	}
+	fmt.Printf("Hint: try %s\n", hint)
+	gameInstance.PrintMovePrompt()
+}
+
+// printAnalysis prints the board with the perfect-play outcome of every open
+// space for the human player.
+func printAnalysis(gameInstance *game.Game) {
+	if isLargeBoard(gameInstance.Board) {
+		fmt.Println("The board is too large to analyze.")
+		gameInstance.PrintMovePrompt()
+		return
+	}
+	if gameInstance.PicksToken() {
+		fmt.Println("Games where the players pick their tokens can't be analyzed yet.")
+		gameInstance.PrintMovePrompt()
+		return
+	}
+	if len(gameInstance.Players) > 2 {
+		fmt.Println("Games of more than two players can't be analyzed yet.")
+		gameInstance.PrintMovePrompt()
+		return
+	}
+
+	player := gameInstance.NextMovePlayer()
+	labels := make([][]string, gameInstance.Board.Rows())
+	for i := range labels {
+		labels[i] = make([]string, gameInstance.Board.Cols())
+	}
+	for _, analysis := range minmax.AnalyzeMoves(*gameInstance.Board, player.Token, searchOptions(gameInstance)) {
+		labels[analysis.Move.Row][analysis.Move.Col] = analysis.Label()
+	}
+	gameInstance.Board.PrintBoardWithLabels(labels)
+	fmt.Println("W<n>: you win in n moves, L<n>: you lose in n moves, D: draw (counting both players' moves)")
+	gameInstance.PrintMovePrompt()
+}
+
+// startAIMove returns the context for a new AI search, which stopAIMove cancels.
+func startAIMove() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelAIMoveMu.Lock()
+	cancelAIMove = cancel
+	cancelAIMoveMu.Unlock()
+	return ctx
+}
+
+// stopAIMove cancels the running AI search. It is a no-op if there is none.
+func stopAIMove() {
+	cancelAIMoveMu.Lock()
+	defer cancelAIMoveMu.Unlock()
+	if cancelAIMove != nil {
+		cancelAIMove()
+		cancelAIMove = nil
+	}
+}
+
 // printNextMoveMessage prints the next move prompt and the current board state.
 func printNextMoveMessage(gameInstance *game.Game, msg string) {
 	if msg != "" {
@@ -223,9 +776,33 @@ func printGameOverMessage(msg string, gameInstance *game.Game) {
 	gameInstance.Board.PrintBoard()
 	fmt.Printf("Game over! %s\n", msg)
 	fmt.Println("")
-	fmt.Println(NEW_GAME_PROMPT)
+
+	// The review replays the game on an empty classic board, with each
+	// player placing their own token
+	if gameInstance.Board.Rules() == board.ClassicRules && !gameInstance.Board.HasBlocked() && gameInstance.Board.StartsEmpty() && len(gameInstance.Players) == 2 && !gameInstance.PicksToken() {
+		fmt.Println("Move review:")
+		for i, review := range minmax.ReviewGame(gameInstance.History(), searchOptions(gameInstance)) {
+			fmt.Printf("%2d. %s\n", i+1, review.Annotation())
+		}
+		fmt.Println("")
+	}
+
+	if usesBook(gameInstance) {
+		winner, _ := gameInstance.Winner()
+		openingBook.Record(gameInstance.History(), winner.Token)
+		if err := saveBook(); err != nil {
+			fmt.Printf("The opening book couldn't be saved: %v\n", err)
+		}
+	}
+	printNewGamePrompt()
 }
 
-func printAILevelPrompt(playerName string) {
+// printAILevelPrompt asks for the AI level of the player, offering the
+// learned agent as well if it can play.
+func printAILevelPrompt(playerName string, offerLearned bool) {
+	if offerLearned {
+		fmt.Printf("Enter the AI level (1-10) for %s, 10 being the most difficult, or 'l' for the learned agent: ", playerName)
+		return
+	}
 	fmt.Printf("Enter the AI level (1-10) for %s, 10 being the most difficult: ", playerName)
 }
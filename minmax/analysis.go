@@ -0,0 +1,156 @@
+package minmax
+
+import (
+	"fmt"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+)
+
+// Outcome is the result of a game with perfect play from both sides.
+type Outcome int
+
+const (
+	Loss Outcome = iota - 1
+	Draw
+	Win
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Win:
+		return "win"
+	case Loss:
+		return "loss"
+	default:
+		return "draw"
+	}
+}
+
+// MoveAnalysis is the perfect-play result of a move for the player making it.
+type MoveAnalysis struct {
+	Move    Move
+	Outcome Outcome
+	Plies   int // plies until the game is won or lost, counting the move itself; 0 for a draw
+}
+
+// Label is a short form of the analysis for display on the board, e.g. "W3"
+// for a move that wins in 3 plies or "D" for a draw.
+func (a MoveAnalysis) Label() string {
+	switch a.Outcome {
+	case Win:
+		return fmt.Sprintf("W%d", a.Plies)
+	case Loss:
+		return fmt.Sprintf("L%d", a.Plies)
+	default:
+		return "D"
+	}
+}
+
+// AnalyzeMoves solves the board for every open space, assuming perfect play
+// after the move. Unlike GetBestMove it always searches to the end of the
+// game, which is only practical on small boards. Only the Misere option
+// applies.
+func AnalyzeMoves(board board.Board, playerToken string, options Options) []MoveAnalysis {
+	opponentToken := "X"
+	if playerToken == "X" {
+		opponentToken = "O"
+	}
+
+	board = *board.Clone()
+	s := newSolver(options.Misere)
+	var analysis []MoveAnalysis
+	for i := 0; i < board.Rows(); i++ {
+		for j := 0; j < board.Cols(); j++ {
+			if board.CanPlace(i, j) {
+				board.PlaceToken(i, j, playerToken)
+				outcome, plies := s.solve(board, opponentToken, playerToken)
+				board.RemoveToken(i, j)
+
+				if outcome == Draw {
+					analysis = append(analysis, MoveAnalysis{Move{Row: i, Col: j}, Draw, 0})
+				} else {
+					analysis = append(analysis, MoveAnalysis{Move{Row: i, Col: j}, -outcome, plies + 1})
+				}
+			}
+		}
+	}
+	return analysis
+}
+
+// solution is a solved position, cached by the solver.
+type solution struct {
+	outcome Outcome
+	plies   int
+}
+
+// solver solves positions to the end of the game. Positions are cached by
+// their canonical form, so a solver can be reused to solve many positions of
+// the same game cheaply.
+type solver struct {
+	misere bool // completing a line loses instead of winning
+	solved map[string]solution
+}
+
+func newSolver(misere bool) *solver {
+	return &solver{misere: misere, solved: make(map[string]solution)}
+}
+
+// solve returns the outcome of the board for the player to move and the
+// number of plies until the game ends. The winning side plays for the
+// quickest win and the losing side for the longest resistance.
+func (s *solver) solve(board board.Board, playerToken string, opponentToken string) (Outcome, int) {
+	// In misère, the player who completes a line loses
+	win := Win
+	if s.misere {
+		win = Loss
+	}
+
+	if board.CheckWinForPlayer(opponentToken) {
+		return -win, 0
+	} else if board.CheckWinForPlayer(playerToken) {
+		return win, 0
+	} else if board.CheckTie() {
+		return Draw, 0
+	}
+
+	// Symmetric positions have the same solution, so they share an entry
+	canonical, _ := board.Canonical()
+	key := playerToken + canonical.Key()
+	if solved, ok := s.solved[key]; ok {
+		return solved.outcome, solved.plies
+	}
+
+	bestOutcome, bestPlies := Loss, -1
+	for i := 0; i < board.Rows(); i++ {
+		for j := 0; j < board.Cols(); j++ {
+			if board.CanPlace(i, j) {
+				board.PlaceToken(i, j, playerToken)
+				outcome, plies := s.solve(board, opponentToken, playerToken)
+				board.RemoveToken(i, j)
+
+				outcome, plies = -outcome, plies+1
+				if betterOutcome(outcome, plies, bestOutcome, bestPlies) {
+					bestOutcome, bestPlies = outcome, plies
+				}
+			}
+		}
+	}
+
+	if bestOutcome == Draw {
+		bestPlies = 0
+	}
+	s.solved[key] = solution{bestOutcome, bestPlies}
+	return bestOutcome, bestPlies
+}
+
+// betterOutcome reports whether outcome a reached in aPlies beats outcome b
+// reached in bPlies.
+func betterOutcome(a Outcome, aPlies int, b Outcome, bPlies int) bool {
+	if a != b {
+		return a > b
+	}
+	if a == Win {
+		return aPlies < bPlies
+	}
+	return aPlies > bPlies
+}
@@ -0,0 +1,102 @@
+package minmax
+
+import (
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+)
+
+// Evaluator scores a position at the depth limit of a search, where the game
+// isn't over yet, for the player holding playerToken. Positive scores favour
+// the player. Scores must stay well inside -WinScore..WinScore, as that is
+// where won and lost games score.
+type Evaluator interface {
+	Evaluate(board board.Board, playerToken string) int
+}
+
+// DefaultEvaluator is the LineEvaluator used unless a game asks for another.
+var DefaultEvaluator Evaluator = LineEvaluator{RunWeight: 8, ForkWeight: 256, SpaceWeight: 1}
+
+// LineEvaluator scores a position of a game won with a line of the player's
+// own token. Each player scores:
+//   - RunWeight to the power of the tokens on every line that holds only
+//     theirs, so an open two is worth RunWeight open ones;
+//   - ForkWeight when they can complete a line on two or more spaces, as the
+//     next move can only block one of them;
+//   - SpaceWeight for every line through each space they hold, which favours
+//     the centre and then the corners.
+//
+// The player's score less their opponents' is the evaluation.
+type LineEvaluator struct {
+	RunWeight   int
+	ForkWeight  int
+	SpaceWeight int
+}
+
+func (e LineEvaluator) Evaluate(b board.Board, playerToken string) int {
+	sign := func(token string) int {
+		if token == playerToken {
+			return 1
+		}
+		return -1
+	}
+
+	score := 0
+	threatening := make(map[string]bool) // tokens one short of a line
+	b.EachLine(func(tokens []string) {
+		owner, count := lineOwner(tokens)
+		if owner == "" {
+			return
+		}
+		run := 1
+		for i := 0; i < count; i++ {
+			run *= e.RunWeight
+		}
+		score += sign(owner) * run
+		if count == len(tokens)-1 {
+			threatening[owner] = true
+		}
+	})
+
+	threats := make(map[string]int)
+	for row := 0; row < b.Rows(); row++ {
+		for col := 0; col < b.Cols(); col++ {
+			switch token := b.GetToken(row, col); token {
+			case " ":
+				for t := range threatening {
+					if b.PlaceToken(row, col, t) {
+						if b.CheckWinAt(row, col) {
+							threats[t]++
+						}
+						b.RemoveToken(row, col)
+					}
+				}
+			case board.Blocked:
+			default:
+				score += sign(token) * e.SpaceWeight * b.LinesThrough(row, col)
+			}
+		}
+	}
+
+	for token, n := range threats {
+		if n >= 2 {
+			score += sign(token) * e.ForkWeight
+		}
+	}
+	return score
+}
+
+// lineOwner returns the token of a line that holds only one kind, and how
+// many of them. It returns an empty token for empty and mixed lines.
+func lineOwner(tokens []string) (string, int) {
+	owner, count := "", 0
+	for _, token := range tokens {
+		switch {
+		case token == " ":
+		case owner == "" || token == owner:
+			owner = token
+			count++
+		default:
+			return "", 0
+		}
+	}
+	return owner, count
+}
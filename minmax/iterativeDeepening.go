@@ -0,0 +1,65 @@
+package minmax
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+)
+
+// GetBestMoveWithin searches the board one depth at a time until the time
+// budget runs out. The move returned is the one found by the deepest search
+// that completed in time; a search interrupted by the deadline is discarded.
+// If not even the shallowest search completes, the first legal move is returned.
+func GetBestMoveWithin(board board.Board, budget time.Duration, playerToken string) (int, int) {
+	row, col, _ := GetBestMoveWithinContext(context.Background(), board, budget, playerToken)
+	return row, col
+}
+
+// GetBestMoveWithinContext is GetBestMoveWithin with cancellation. Running out
+// of time is not an error, but if ctx itself is done the best move so far is
+// returned together with the context's error.
+func GetBestMoveWithinContext(ctx context.Context, board board.Board, budget time.Duration, playerToken string) (int, int, error) {
+	return GetBestMoveWithinOptions(ctx, board, budget, playerToken, Options{})
+}
+
+// GetBestMoveWithinOptions is GetBestMoveWithinContext with the given options.
+// When the player picks the token to place, use GetBestPlacementWithin to
+// learn it as well.
+func GetBestMoveWithinOptions(ctx context.Context, board board.Board, budget time.Duration, playerToken string, options Options) (int, int, error) {
+	move, err := GetBestPlacementWithin(ctx, board, budget, playerToken, options)
+	return move.Row, move.Col, err
+}
+
+// GetBestPlacementWithin is GetBestMoveWithinOptions returning the move with
+// the token to place, like GetBestPlacement.
+func GetBestPlacementWithin(ctx context.Context, board board.Board, budget time.Duration, playerToken string, options Options) (Move, error) {
+	best := Move{Row: -1, Col: -1}
+	if moves := newSearch(ctx, 0, playerToken, options).moves(board, playerToken); len(moves) > 0 {
+		best = moves[0]
+	}
+
+	openSpaces := 0
+	for i := 0; i < board.Rows(); i++ {
+		for j := 0; j < board.Cols(); j++ {
+			if board.GetToken(i, j) == " " {
+				openSpaces++
+			}
+		}
+	}
+
+	searchCtx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	// A depth of openSpaces-1 already searches every remaining move, so
+	// there is nothing to gain from going deeper.
+	for maxDepth := 0; maxDepth < openSpaces; maxDepth++ {
+		move, _, err := GetBestPlacement(searchCtx, board, maxDepth, playerToken, options)
+		if err != nil {
+			break
+		}
+		best = move
+	}
+
+	return best, ctx.Err()
+}
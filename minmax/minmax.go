@@ -1,97 +1,375 @@
 package minmax
 
 import (
+	"context"
 	"math"
+	"time"
 
 	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/game"
 )
 
+// Move is a space on the board.
+type Move struct {
+	Row   int
+	Col   int
+	Token string // the token placed, set only in Wild and Numerical searches where the player picks it
+}
+
+// MoveScore is the minmax score of one of the AI player's moves.
+type MoveScore struct {
+	Move  Move
+	Score int
+}
+
+// Stats describes the work done by a search.
+type Stats struct {
+	Nodes              int           // positions visited, including the root moves
+	LeafEvaluations    int           // positions scored without searching further
+	MaxDepth           int           // deepest ply reached, counting the root move as 1
+	Cutoffs            int           // alpha-beta cutoffs, only SearchPosition prunes the tree
+	Duration           time.Duration // time taken by the search
+	PrincipalVariation []Move        // the best line of play found, starting with the chosen move
+	RootScores         []MoveScore   // the score of every move that was searched, in search order
+}
+
 func GetBestMove(board board.Board, maxDepth int, playerToken string) (int, int) {
-	bestRow, bestCol := -1, -1
+	row, col, _ := GetBestMoveContext(context.Background(), board, maxDepth, playerToken)
+	return row, col
+}
+
+// GetBestMoveContext is GetBestMove with cancellation. If ctx is done before
+// the search finishes, it returns the best of the moves scored so far (or
+// -1, -1 if there are none) together with the context's error.
+func GetBestMoveContext(ctx context.Context, board board.Board, maxDepth int, playerToken string) (int, int, error) {
+	row, col, _, err := GetBestMoveStats(ctx, board, maxDepth, playerToken)
+	return row, col, err
+}
+
+// GetBestMoveStats is GetBestMoveContext that also reports what the search did.
+func GetBestMoveStats(ctx context.Context, board board.Board, maxDepth int, playerToken string) (int, int, Stats, error) {
+	return GetBestMoveWithOptions(ctx, board, maxDepth, playerToken, Options{})
+}
+
+// Options changes the rules or the behaviour of a search.
+type Options struct {
+	Random bool // visit the moves in a random order, like GetBestMoveWithRandom
+	Misere bool // completing a line loses instead of winning
+	Wild   bool // either player may place X or O, and a line counts for whoever completed it
+
+	// Numerical has X place the odd numbers and O the even ones, see
+	// game.NumberTokens. The board's line rule decides which lines win, and
+	// they count for whoever completed them.
+	Numerical bool
+
+	// OrderChaos has both players place X or O, with the player holding
+	// game.OrderToken playing Order, who wins with a line of either, and
+	// the other playing Chaos, who wins by filling the board without one.
+	// Positions at the depth limit are scored by orderPotential.
+	OrderChaos bool
+
+	// Opponents are the tokens of the other players, in the order they move
+	// after the AI player. Left empty, the opponent is whichever of X and O
+	// the AI player doesn't hold. With more than one opponent the search is
+	// paranoid: every opponent is assumed to play against the AI player,
+	// whoever wins in the end.
+	Opponents []string
+
+	// Evaluator scores the positions at the depth limit, which otherwise
+	// score as a draw. It is only used in searches where the players' lines
+	// are made of their own token, not when they pick the token to place,
+	// and its score is negated in misère. With an Evaluator, won games score
+	// WinScore less the plies it took to win, so they still outweigh any
+	// evaluation and the quickest win is preferred.
+	Evaluator Evaluator
+}
+
+// picksToken reports whether the player picks the token to place.
+func (o Options) picksToken() bool {
+	return o.Wild || o.Numerical || o.OrderChaos
+}
+
+// GetBestMoveWithOptions is GetBestMoveStats with the given options. When the
+// player picks the token to place, use GetBestPlacement to learn it as well.
+func GetBestMoveWithOptions(ctx context.Context, board board.Board, maxDepth int, playerToken string, options Options) (int, int, Stats, error) {
+	move, stats, err := GetBestPlacement(ctx, board, maxDepth, playerToken, options)
+	return move.Row, move.Col, stats, err
+}
+
+// GetBestPlacement is GetBestMoveWithOptions returning the move with the token
+// to place, for Wild and Numerical searches where the player picks it. In
+// other searches the token is left empty, as it is always the player's own.
+func GetBestPlacement(ctx context.Context, board board.Board, maxDepth int, playerToken string, options Options) (Move, Stats, error) {
+	s := newSearch(ctx, maxDepth, playerToken, options)
+
+	// Search a copy, as the caller's board shares its spaces with ours
+	return s.bestMove(*board.Clone())
+}
+
+// search holds the state shared by every node of a single search.
+type search struct {
+	ctx         context.Context
+	maxDepth    int
+	playerToken string
+	opponents   []string // the opponents' tokens in turn order
+	options     Options
+	stats       Stats
+}
+
+func newSearch(ctx context.Context, maxDepth int, playerToken string, options Options) *search {
+	opponents := options.Opponents
+	if len(opponents) == 0 {
+		opponents = []string{"X"}
+		if playerToken == "X" {
+			opponents = []string{"O"}
+		}
+	}
+
+	return &search{
+		ctx:         ctx,
+		maxDepth:    maxDepth,
+		playerToken: playerToken,
+		opponents:   opponents,
+		options:     options,
+	}
+}
+
+// bestMove scores every move of the AI player and returns the best one.
+func (s *search) bestMove(board board.Board) (Move, Stats, error) {
+	start := time.Now()
+	defer func() { s.stats.Duration = time.Since(start) }()
+
+	best := Move{Row: -1, Col: -1}
 	var bestScore int
 
 	bestScore = math.MinInt
 
-	// iterate over the board and find the first empty space
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			if board.GetToken(i, j) == " " {
+	for _, move := range s.moves(board, s.playerToken) {
 
-				// Simulate a move for the AI player
-				board.PlaceToken(i, j, playerToken)
+		// Simulate a move for the AI player
+		board.PlaceToken(move.Row, move.Col, s.token(move, s.playerToken))
 
-				// Call minmax to get the score for the move
-				score := minmax(board, 0, false, maxDepth, playerToken)
+		// Call minmax to get the score for the move
+		score, line, err := s.minmax(board, 0, 1)
 
-				// Undo the move
-				board.RemoveToken(i, j)
+		// Undo the move
+		board.RemoveToken(move.Row, move.Col)
 
-				if score > bestScore {
-					bestScore = score
-					bestRow = i
-					bestCol = j
-				}
-			}
+		if err != nil {
+			return best, s.stats, err
+		}
+
+		s.stats.RootScores = append(s.stats.RootScores, MoveScore{move, score})
+		if score > bestScore {
+			bestScore = score
+			best = move
+			s.stats.PrincipalVariation = append([]Move{move}, line...)
 		}
 	}
 
-	return bestRow, bestCol
+	return best, s.stats, nil
 }
 
-// _minmax is a recursive function that implements the minimax algorithm
-func minmax(board board.Board, depth int, isMaximizing bool, maxDepth int, playerToken string) int {
+// minmax is a recursive function that implements the minimax algorithm. It
+// returns the score of the board and the line of play that leads to it. The
+// turn is 0 when the AI player is to move, and i when opponent i-1 is.
+func (s *search) minmax(board board.Board, depth int, turn int) (int, []Move, error) {
 
-	opponentToken := "X"
-	if playerToken == "X" {
-		opponentToken = "O"
+	if err := s.ctx.Err(); err != nil {
+		return 0, nil, err
 	}
 
-	if board.CheckWinForPlayer(opponentToken) {
-		return -1
-	} else if board.CheckWinForPlayer(playerToken) {
-		return 1
-	} else if board.CheckTie() || depth == maxDepth {
-		return 0
+	s.stats.Nodes++
+	s.stats.MaxDepth = max(s.stats.MaxDepth, depth+1)
+
+	// In misère, the player who completes a line loses
+	win := 1
+	if s.options.Misere {
+		win = -1
+	}
+
+	// The AI player moves on maximizing turns, the opponents on minimizing ones
+	isMaximizing := turn == 0
+
+	if s.options.OrderChaos {
+		if score, ok := s.orderChaosScore(board, depth); ok {
+			s.stats.LeafEvaluations++
+			return score, nil, nil
+		}
+	} else if s.options.picksToken() && board.CheckWin() {
+		// When the players pick their tokens, a line counts for the player
+		// who just moved, whatever it holds
+		s.stats.LeafEvaluations++
+		if isMaximizing {
+			return s.decisive(-win, depth), nil, nil
+		}
+		return s.decisive(win, depth), nil, nil
+	} else if score, ok := s.lineScore(board, depth, win); ok {
+		s.stats.LeafEvaluations++
+		return score, nil, nil
 	}
 
 	depth += 1
-	if isMaximizing {
-		maxEval := math.MinInt
-		for i := 0; i < 3; i++ {
-			for j := 0; j < 3; j++ {
-				if board.GetToken(i, j) == " " {
-					// Simulate a move for the AI player
-					board.PlaceToken(i, j, playerToken)
-
-					// Recursively call _minmax with the new board state
-					score := minmax(board, depth, false, maxDepth, playerToken)
-
-					// Undo the move
-					board.RemoveToken(i, j)
-					maxEval = max(maxEval, score)
-				}
+
+	token := s.playerToken
+	bestEval := math.MinInt
+	if !isMaximizing {
+		token = s.opponents[turn-1]
+		bestEval = math.MaxInt
+	}
+	next := (turn + 1) % (len(s.opponents) + 1)
+
+	var bestLine []Move
+	for _, move := range s.moves(board, token) {
+
+		// Simulate the move
+		board.PlaceToken(move.Row, move.Col, s.token(move, token))
+
+		// Recursively call minmax with the new board state
+		score, line, err := s.minmax(board, depth, next)
+
+		// Undo the move
+		board.RemoveToken(move.Row, move.Col)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if (isMaximizing && score > bestEval) || (!isMaximizing && score < bestEval) {
+			bestEval = score
+			bestLine = append([]Move{move}, line...)
+		}
+	}
+	return bestEval, bestLine, nil
+}
+
+// lineScore scores a position where each player's lines are made of their
+// own token, and reports whether the search ends there.
+func (s *search) lineScore(board board.Board, depth int, win int) (int, bool) {
+	for _, opponent := range s.opponents {
+		if board.CheckWinForPlayer(opponent) {
+			return s.decisive(-win, depth), true
+		}
+	}
+	if board.CheckWinForPlayer(s.playerToken) {
+		return s.decisive(win, depth), true
+	} else if board.CheckTie() {
+		return 0, true
+	} else if depth == s.maxDepth {
+		if s.options.Evaluator != nil && !s.options.picksToken() {
+			return win * s.options.Evaluator.Evaluate(board, s.playerToken), true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// decisive returns the score of a won game, won by the AI player if win is
+// 1 and lost if it is -1. Without an Evaluator that is win itself.
+func (s *search) decisive(win int, depth int) int {
+	if s.options.Evaluator == nil {
+		return win
+	}
+	return win * (WinScore - depth)
+}
+
+// orderChaosScore scores a position of Order and Chaos for the AI player,
+// and reports whether the search ends there. Games are won by WinScore less
+// the plies it took, so the quickest win and the slowest loss are preferred.
+func (s *search) orderChaosScore(board board.Board, depth int) (int, bool) {
+	sign := 1
+	if s.playerToken != game.OrderToken {
+		sign = -1
+	}
+
+	switch {
+	case board.CheckWin():
+		return sign * (WinScore - depth), true
+	case board.CheckTie():
+		return -sign * (WinScore - depth), true
+	case depth == s.maxDepth:
+		return sign * orderPotential(board), true
+	}
+	return 0, false
+}
+
+// orderWeights score a run of WinLength spaces for Order by the tokens in it,
+// all of them the same. A full run that doesn't win is an overline, which
+// Order can't use any more.
+var orderWeights = []int{1, 2, 8, 64, 512, 0}
+
+// orderPotential estimates Order's chances by the runs that Order can still
+// turn into a line, which are the ones holding only X or only O. Each placed
+// token of the other kind takes a run away for good, which is how Chaos wins.
+func orderPotential(board board.Board) int {
+	potential := 0
+	board.EachLine(func(tokens []string) {
+		x, o := 0, 0
+		for _, token := range tokens {
+			switch token {
+			case "X":
+				x++
+			case "O":
+				o++
 			}
 		}
-		return maxEval
+		if (x == 0 || o == 0) && max(x, o) < len(orderWeights) {
+			potential += orderWeights[max(x, o)]
+		}
+	})
+	return potential
+}
 
-	} else {
-		minEval := math.MaxInt
-		for i := 0; i < 3; i++ {
-			for j := 0; j < 3; j++ {
-				if board.GetToken(i, j) == " " {
+// moves returns the moves to search for the player holding playerToken. They
+// are the open spaces, and when the player picks the token, each of them
+// once with every token the player may place.
+func (s *search) moves(board board.Board, playerToken string) []Move {
+	spaces := s.openSpaces(board)
 
-					// Simulate a move for the human player
-					board.PlaceToken(i, j, opponentToken)
+	var tokens []string
+	switch {
+	case s.options.Numerical:
+		tokens = game.NumberTokens(&board, playerToken)
+	case s.options.Wild || s.options.OrderChaos:
+		tokens = []string{"X", "O"}
+	default:
+		return spaces
+	}
+
+	moves := make([]Move, 0, len(tokens)*len(spaces))
+	for _, space := range spaces {
+		for _, token := range tokens {
+			moves = append(moves, Move{space.Row, space.Col, token})
+		}
+	}
+	return moves
+}
 
-					// Recursively call _minmax with the new board state
-					score := minmax(board, depth, true, maxDepth, playerToken)
+// token returns the token the move places for a player holding playerToken.
+func (s *search) token(move Move, playerToken string) string {
+	if move.Token != "" {
+		return move.Token
+	}
+	return playerToken
+}
+
+// openSpaces returns the spaces a token can be placed on, in the order they
+// should be searched.
+func (s *search) openSpaces(board board.Board) []Move {
+	var moves []Move
+	if s.options.Random && !board.CheckTie() {
+		rs := NewRandomSpot(board)
+		for spot := rs.GetNextOpenMove(board); spot != nil; spot = rs.GetNextOpenMove(board) {
+			moves = append(moves, Move{Row: spot.row, Col: spot.col})
+		}
+		return moves
+	}
 
-					// Undo the move
-					board.RemoveToken(i, j)
-					minEval = min(minEval, score)
-				}
+	for i := 0; i < board.Rows(); i++ {
+		for j := 0; j < board.Cols(); j++ {
+			if board.CanPlace(i, j) {
+				moves = append(moves, Move{Row: i, Col: j})
 			}
 		}
-		return minEval
 	}
+	return moves
 }
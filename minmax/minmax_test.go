@@ -1,9 +1,12 @@
 package minmax
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/game"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -16,7 +19,7 @@ func TestGetestMoveSuite(t *testing.T) {
 	suite.Run(t, new(bestMoveSuite))
 }
 
-func (s *bestMoveSuite) SetupSuite() {
+func (s *bestMoveSuite) SetupTest() {
 	s.board = *board.NewBoard()
 }
 
@@ -70,11 +73,13 @@ func (s *bestMoveSuite) TestGetBestMoveOneMoveLeft_Random() {
 
 func (s *bestMoveSuite) TestGetBestMoveBlockOpponentWinningMove_Random() {
 
-	openSpaces := 4
+	// Blocking has to be the only move that doesn't lose, otherwise the
+	// random search may pick any of the equally scored moves.
+	openSpaces := 6
 	spaces := [3][3]string{
 		{"X", "X", " "},
-		{"O", " ", " "},
-		{"O", "X", " "},
+		{" ", "O", " "},
+		{" ", " ", " "},
 	}
 	s.board.SetStartingBoard(spaces)
 	s.evaluateRandomMove(0, 2, openSpaces)
@@ -92,6 +97,416 @@ func (s *bestMoveSuite) TestGetBestMoveWinningMove_Random() {
 	s.evaluateRandomMove(1, 2, openSpaces)
 }
 
+func (s *bestMoveSuite) TestGetBestMoveWithinWinningMove() {
+
+	spaces := [3][3]string{
+		{"X", "X", " "},
+		{"O", "O", " "},
+		{"X", " ", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+	row, col := GetBestMoveWithin(s.board, time.Second, "O")
+	s.Equal(1, row)
+	s.Equal(2, col)
+}
+
+func (s *bestMoveSuite) TestGetBestMoveWithinNoTime() {
+
+	spaces := [3][3]string{
+		{"X", "X", " "},
+		{"O", " ", " "},
+		{"O", "X", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+	row, col := GetBestMoveWithin(s.board, 0, "O")
+	s.Equal(" ", s.board.GetToken(row, col))
+}
+
+func (s *bestMoveSuite) TestGetBestMoveContextCancelled() {
+
+	s.board.InitBoard()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	row, col, err := GetBestMoveContext(ctx, s.board, 8, "X")
+	s.ErrorIs(err, context.Canceled)
+	s.Equal(-1, row)
+	s.Equal(-1, col)
+
+	row, col, err = GetBestMoveWithRandomContext(ctx, s.board, 8, "X")
+	s.ErrorIs(err, context.Canceled)
+	s.Equal(-1, row)
+	s.Equal(-1, col)
+}
+
+func (s *bestMoveSuite) TestGetBestMoveContextDeadline() {
+
+	s.board.InitBoard()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	// Solving the empty board takes far longer than the deadline
+	start := time.Now()
+	_, _, err := GetBestMoveContext(ctx, s.board, 1000000, "X")
+	s.Less(time.Since(start), time.Second)
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.Equal(" ", s.board.GetToken(1, 1), "the board must be restored after a cancelled search")
+}
+
+func (s *bestMoveSuite) TestGetBestMoveWithinContextCancelled() {
+
+	s.board.InitBoard()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	row, col, err := GetBestMoveWithinContext(ctx, s.board, time.Second, "X")
+	s.ErrorIs(err, context.Canceled)
+	s.Equal(" ", s.board.GetToken(row, col))
+}
+
+func (s *bestMoveSuite) TestGetBestMoveStats() {
+
+	spaces := [3][3]string{
+		{"X", "X", " "},
+		{"O", "O", " "},
+		{"X", " ", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+	row, col, stats, err := GetBestMoveStats(context.Background(), s.board, 3, "O")
+	s.NoError(err)
+	s.Equal(1, row)
+	s.Equal(2, col)
+
+	s.Len(stats.RootScores, 4)
+	s.Equal(Move{Row: 1, Col: 2}, stats.PrincipalVariation[0])
+	s.Greater(stats.Nodes, stats.LeafEvaluations)
+	s.Equal(4, stats.MaxDepth)
+	for _, rootScore := range stats.RootScores {
+		if rootScore.Move == (Move{Row: 1, Col: 2}) {
+			s.Equal(1, rootScore.Score)
+		}
+	}
+}
+
+func (s *bestMoveSuite) TestAnalyzeMoves() {
+
+	spaces := [3][3]string{
+		{"X", "X", " "},
+		{"O", "O", " "},
+		{"X", " ", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+	labels := map[Move]string{}
+	for _, analysis := range AnalyzeMoves(s.board, "O", Options{}) {
+		labels[analysis.Move] = analysis.Label()
+	}
+	s.Equal(map[Move]string{
+		{Row: 0, Col: 2}: "D",
+		{Row: 1, Col: 2}: "W1",
+		{Row: 2, Col: 1}: "L2",
+		{Row: 2, Col: 2}: "L2",
+	}, labels)
+}
+
+func (s *bestMoveSuite) TestReviewGame() {
+
+	history := []game.Move{
+		{Row: 1, Col: 1, Token: "X"},
+		{Row: 0, Col: 1, Token: "O"}, // an edge reply to the center loses
+		{Row: 0, Col: 0, Token: "X"},
+		{Row: 2, Col: 2, Token: "O"},
+		{Row: 1, Col: 2, Token: "X"}, // misses the double threat at 1,0 and lets O draw
+	}
+	reviews := ReviewGame(history, Options{})
+	s.Len(reviews, len(history))
+
+	var blunders []int
+	for i, review := range reviews {
+		if review.Blunder() {
+			blunders = append(blunders, i)
+		}
+	}
+	s.Equal([]int{1, 4}, blunders)
+	s.Equal(Draw, reviews[1].Before)
+	s.Equal(Loss, reviews[1].After)
+	s.Equal("O 0,1: blunder, turns a draw into a loss", reviews[1].Annotation())
+}
+
+func (s *bestMoveSuite) TestGetBestMoveMisereAvoidsLine() {
+
+	spaces := [3][3]string{
+		{"O", "O", " "},
+		{" ", "X", " "},
+		{" ", "X", "X"},
+	}
+	s.board.SetStartingBoard(spaces)
+
+	// Completing the top row loses, and every move but 1,2 lets X force O
+	// into another line
+	row, col, _, err := GetBestMoveWithOptions(context.Background(), s.board, 4, "O", Options{Misere: true})
+	s.NoError(err)
+	s.Equal(1, row)
+	s.Equal(2, col)
+}
+
+func (s *bestMoveSuite) TestAnalyzeMovesMisereEmptyBoard() {
+
+	s.board.InitBoard()
+
+	// Misère tic-tac-toe is a draw with perfect play
+	best := Loss
+	for _, analysis := range AnalyzeMoves(s.board, "X", Options{Misere: true}) {
+		best = max(best, analysis.Outcome)
+	}
+	s.Equal(Draw, best)
+}
+
+func (s *bestMoveSuite) TestAnalyzeMovesTorusEmptyBoard() {
+
+	// On a 3x3 torus any two spaces share a line, so X wins with perfect play
+	b := board.NewBoardWithRules(board.Rules{Rows: 3, Cols: 3, WinLength: 3, Torus: true})
+	best := Loss
+	for _, analysis := range AnalyzeMoves(*b, "X", Options{}) {
+		best = max(best, analysis.Outcome)
+	}
+	s.Equal(Win, best)
+}
+
+func (s *bestMoveSuite) TestGetBestMoveEvaluatorTakesCentre() {
+
+	// Without an evaluator every move scores a draw at depth 0
+	row, col, _, err := GetBestMoveWithOptions(context.Background(), s.board, 0, "X", Options{Evaluator: DefaultEvaluator})
+	s.NoError(err)
+	s.Equal(1, row)
+	s.Equal(1, col)
+}
+
+func (s *bestMoveSuite) TestGetBestMoveEvaluatorPrefersWin() {
+
+	spaces := [3][3]string{
+		{"X", "X", " "},
+		{"O", "O", " "},
+		{" ", " ", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+	row, col, stats, err := GetBestMoveWithOptions(context.Background(), s.board, 2, "X", Options{Evaluator: DefaultEvaluator})
+	s.NoError(err)
+	s.Equal(0, row)
+	s.Equal(2, col)
+	for _, rootScore := range stats.RootScores {
+		if rootScore.Move == (Move{Row: 0, Col: 2}) {
+			s.Equal(WinScore, rootScore.Score)
+		}
+	}
+}
+
+func (s *bestMoveSuite) TestLineEvaluatorFork() {
+
+	// X can complete the top row and the left column
+	spaces := [3][3]string{
+		{"X", " ", "X"},
+		{" ", "O", " "},
+		{"X", " ", "O"},
+	}
+	s.board.SetStartingBoard(spaces)
+	forks := LineEvaluator{ForkWeight: 1}
+	s.Equal(1, forks.Evaluate(s.board, "X"))
+	s.Equal(-1, forks.Evaluate(s.board, "O"))
+
+	// The centre lies on four lines, a corner on three and an edge on two
+	spaces = [3][3]string{
+		{"X", "X", " "},
+		{" ", "O", " "},
+		{" ", " ", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+	s.Equal(3+2-4, LineEvaluator{SpaceWeight: 1}.Evaluate(s.board, "X"))
+}
+
+func (s *bestMoveSuite) TestProfileChoose() {
+
+	scores := []MoveScore{
+		{Move{Row: 0, Col: 0}, 5},
+		{Move{Row: 1, Col: 1}, 30},
+		{Move{Row: 2, Col: 2}, -WinScore},
+	}
+	s.Equal(Move{Row: 1, Col: 1}, Profile{}.Choose(scores))
+	for i := 0; i < 20; i++ {
+		s.NotEqual(Move{Row: 1, Col: 1}, Profile{Mistake: 1}.Choose(scores))
+
+		// A lost game is never worth the risk, however hot the choice
+		s.NotEqual(Move{Row: 2, Col: 2}, Profile{Temperature: 1000}.Choose(scores))
+	}
+
+	s.Equal(Profiles[0], ProfileFor(-1))
+	s.Equal(Profile{Depth: 9}, ProfileFor(12))
+}
+
+func (s *bestMoveSuite) TestGetPlacementWithProfileBlocks() {
+
+	spaces := [3][3]string{
+		{"X", "X", " "},
+		{"O", " ", " "},
+		{" ", " ", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+	move, _, err := GetPlacementWithProfile(context.Background(), s.board, ProfileFor(9), "O", Options{Evaluator: DefaultEvaluator})
+	s.NoError(err)
+	s.Equal(Move{Row: 0, Col: 2}, move)
+}
+
+func (s *bestMoveSuite) TestGetBestMoveConnectFour() {
+
+	b := board.NewBoardWithRules(board.ConnectFourRules)
+	for _, col := range []int{0, 0, 0} {
+		b.PlaceToken(b.DropRow(col), col, "X")
+	}
+	b.PlaceToken(b.DropRow(1), 1, "O")
+	b.PlaceToken(b.DropRow(2), 2, "O")
+
+	// O must block the column, as a drop anywhere else lets X win on top
+	row, col := GetBestMove(*b, 2, "O")
+	s.Equal(2, row)
+	s.Equal(0, col)
+}
+
+func (s *bestMoveSuite) TestGetBestMoveThreePlayersBlocksLastPlayer() {
+
+	b := board.NewBoardWithRules(board.Rules{Rows: 5, Cols: 5, WinLength: 3})
+	b.PlaceToken(0, 0, "X")
+	b.PlaceToken(1, 3, "X")
+	b.PlaceToken(0, 4, "O")
+	b.PlaceToken(3, 3, "O")
+	b.PlaceToken(4, 0, "△")
+	b.PlaceToken(4, 1, "△")
+
+	// △ moves after O, so X must block it now, as O won't
+	move, _, err := GetBestPlacement(context.Background(), *b, 2, "X", Options{Opponents: []string{"O", "△"}})
+	s.NoError(err)
+	s.Equal(Move{Row: 4, Col: 2}, move)
+}
+
+// newOrderChaosBoard returns an Order and Chaos board with four X at the
+// start of the top row.
+func newOrderChaosBoard() *board.Board {
+	b := board.NewBoardWithRules(board.OrderChaosRules)
+	for col := 0; col < 4; col++ {
+		b.PlaceToken(0, col, "X")
+	}
+	b.PlaceToken(3, 3, "O")
+	return b
+}
+
+func (s *bestMoveSuite) TestGetBestPlacementOrderCompletesFive() {
+
+	b := newOrderChaosBoard()
+	move, _, err := GetBestPlacement(context.Background(), *b, 1, game.OrderToken, Options{OrderChaos: true})
+	s.NoError(err)
+	s.Equal(Move{Row: 0, Col: 4, Token: "X"}, move)
+}
+
+func (s *bestMoveSuite) TestGetBestPlacementChaosBlocksFive() {
+
+	b := newOrderChaosBoard()
+	move, _, err := GetBestPlacement(context.Background(), *b, 1, "O", Options{OrderChaos: true})
+	s.NoError(err)
+
+	// Chaos can block with O at 0,4, or with X at 0,5 so that a fifth X
+	// makes an overline, but must leave Order no line to complete
+	b.PlaceToken(move.Row, move.Col, move.Token)
+	for row := 0; row < b.Rows(); row++ {
+		for col := 0; col < b.Cols(); col++ {
+			for _, token := range []string{"X", "O"} {
+				if b.PlaceToken(row, col, token) {
+					s.False(b.CheckWin(), "Order wins with %s at %d,%d after %v", token, row, col, move)
+					b.RemoveToken(row, col)
+				}
+			}
+		}
+	}
+}
+
+func (s *bestMoveSuite) TestGetBestPlacementWildCompletesOpponentsLine() {
+
+	spaces := [3][3]string{
+		{"O", "O", " "},
+		{"X", " ", " "},
+		{" ", " ", "X"},
+	}
+	s.board.SetStartingBoard(spaces)
+
+	// In Wild the line wins for whoever completes it, even with O's tokens
+	move, _, err := GetBestPlacement(context.Background(), s.board, 1, "X", Options{Wild: true})
+	s.NoError(err)
+	s.Equal(Move{Row: 0, Col: 2, Token: "O"}, move)
+}
+
+func (s *bestMoveSuite) TestGetBestPlacementWildLeavesNoLine() {
+
+	spaces := [3][3]string{
+		{"X", " ", " "},
+		{" ", " ", " "},
+		{" ", " ", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+
+	// Two of a kind in a line with the third space open hands the opponent the win
+	move, _, err := GetBestPlacement(context.Background(), s.board, 2, "O", Options{Wild: true})
+	s.NoError(err)
+	s.board.PlaceToken(move.Row, move.Col, move.Token)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for _, token := range []string{"X", "O"} {
+				if s.board.PlaceToken(i, j, token) {
+					s.False(s.board.CheckWin(), "%v lets the opponent win with %s at %d,%d", move, token, i, j)
+					s.board.RemoveToken(i, j)
+				}
+			}
+		}
+	}
+}
+
+func (s *bestMoveSuite) TestGetBestPlacementEvaluatorSkipsPickedTokens() {
+
+	// The LineEvaluator counts a line of X as X's, which it isn't when either
+	// player may complete it, so these positions score as a draw at the limit
+	spaces := [3][3]string{
+		{"X", " ", " "},
+		{" ", " ", " "},
+		{" ", " ", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+	_, stats, err := GetBestPlacement(context.Background(), s.board, 0, "X", Options{Wild: true, Evaluator: DefaultEvaluator})
+	s.NoError(err)
+	for _, rootScore := range stats.RootScores {
+		s.Equal(0, rootScore.Score, "%v", rootScore.Move)
+	}
+
+	s.board.InitBoard()
+	s.board.SetLineRule(board.SumsTo(15))
+	_, stats, err = GetBestPlacement(context.Background(), s.board, 0, "X", Options{Numerical: true, Evaluator: DefaultEvaluator})
+	s.NoError(err)
+	for _, rootScore := range stats.RootScores {
+		s.Equal(0, rootScore.Score, "%v", rootScore.Move)
+	}
+}
+
+func (s *bestMoveSuite) TestGetBestPlacementNumerical() {
+
+	spaces := [3][3]string{
+		{"1", " ", "5"},
+		{"2", " ", " "},
+		{" ", " ", "4"},
+	}
+	s.board.SetStartingBoard(spaces)
+	s.board.SetLineRule(board.SumsTo(15))
+
+	// X places the odd numbers, and only 9 is left to make 15 on the top row
+	move, _, err := GetBestPlacement(context.Background(), s.board, 1, "X", Options{Numerical: true})
+	s.NoError(err)
+	s.Equal(Move{Row: 0, Col: 1, Token: "9"}, move)
+}
+
 func (s *bestMoveSuite) evaluateRandomMove(expectedRow int, expectedCol int, openSpaces int) {
 	row, col := GetBestMoveWithRandom(s.board, openSpaces-1, "O")
 	s.Equal(expectedRow, row)
@@ -102,3 +517,91 @@ func (s *bestMoveSuite) evaluateMove(expectedRow int, expectedCol int, openSpace
 	s.Equal(expectedRow, row)
 	s.Equal(expectedCol, col)
 }
+
+// boardPosition plays tic-tac-toe through the Position interface.
+type boardPosition struct {
+	board  board.Board
+	tokens [2]string
+	toMove int
+}
+
+func (p *boardPosition) Moves() []Move {
+	var moves []Move
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if p.board.GetToken(i, j) == " " {
+				moves = append(moves, Move{Row: i, Col: j})
+			}
+		}
+	}
+	return moves
+}
+
+func (p *boardPosition) Play(move Move) {
+	p.board.PlaceToken(move.Row, move.Col, p.tokens[p.toMove])
+	p.toMove = 1 - p.toMove
+}
+
+func (p *boardPosition) Undo(move Move) {
+	p.board.RemoveToken(move.Row, move.Col)
+	p.toMove = 1 - p.toMove
+}
+
+func (p *boardPosition) Outcome() (bool, Outcome) {
+	if p.board.CheckWinForPlayer(p.tokens[1-p.toMove]) {
+		return true, Loss
+	}
+	return p.board.CheckTie(), Draw
+}
+
+func (p *boardPosition) Evaluate() int {
+	return 0
+}
+
+func (s *bestMoveSuite) TestSearchPosition() {
+
+	spaces := [3][3]string{
+		{"X", "X", " "},
+		{" ", "O", " "},
+		{" ", " ", " "},
+	}
+	s.board.SetStartingBoard(spaces)
+	position := &boardPosition{board: s.board, tokens: [2]string{"O", "X"}}
+
+	result, err := SearchPosition[Move](context.Background(), position, 9)
+	s.NoError(err)
+	s.Equal(Move{Row: 0, Col: 2}, result.Move)
+	s.Equal(0, result.Score)
+	s.Len(result.PrincipalVariation, 6)
+	s.Greater(result.Stats.Cutoffs, 0)
+
+	_, _, stats, _ := GetBestMoveStats(context.Background(), s.board, 9, "O")
+	s.Less(result.Stats.Nodes, stats.Nodes, "alpha-beta should visit fewer nodes than minmax")
+
+	result, err = SearchPositionWithin[Move](context.Background(), position, time.Second, 9)
+	s.NoError(err)
+	s.Equal(Move{Row: 0, Col: 2}, result.Move)
+}
+
+func (s *bestMoveSuite) TestPlayWithin() {
+
+	spaces := [3][3]string{
+		{"X", "X", " "},
+		{" ", "O", " "},
+		{" ", " ", " "},
+	}
+	for _, budget := range []time.Duration{0, time.Second} {
+		s.board.SetStartingBoard(spaces)
+		position := &boardPosition{board: s.board, tokens: [2]string{"O", "X"}}
+		s.NoError(PlayWithin[Move](context.Background(), position, AIDepth(9, 1, 1), budget, 9))
+		s.Equal("O", s.board.GetToken(0, 2))
+		s.Equal(1, position.toMove)
+	}
+
+	s.board.SetStartingBoard([3][3]string{
+		{"X", "O", "X"},
+		{"X", "O", "O"},
+		{"O", "X", "X"},
+	})
+	s.Error(PlayWithin[Move](context.Background(), &boardPosition{board: s.board}, 1, 0, 9))
+}
@@ -0,0 +1,211 @@
+package minmax
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WinScore is the score SearchPosition gives a won game. Wins further down
+// the tree score slightly less, so the quickest win is preferred.
+const WinScore = 1000000
+
+// Position is a game state that SearchPosition can explore, for games that
+// don't fit on a single 3x3 board. Moves are played and undone in place.
+type Position[M comparable] interface {
+	// Moves returns the legal moves of the player to move.
+	Moves() []M
+
+	// Play makes a move returned by Moves.
+	Play(move M)
+
+	// Undo takes back a move, which must be the last one played.
+	Undo(move M)
+
+	// Outcome reports whether the game is over and, if so, the result for
+	// the player to move.
+	Outcome() (bool, Outcome)
+
+	// Evaluate estimates how good a position that isn't over is for the
+	// player to move. Scores must stay well inside -WinScore..WinScore.
+	Evaluate() int
+}
+
+// Tokens are the tokens of the two players of a Position, indexed by player.
+var Tokens = [2]string{"X", "O"}
+
+// OutcomeFor is Position.Outcome for games of two players indexed 0 and 1:
+// whether the game is over and the result for toMove, given the winner or
+// -1 for a draw.
+func OutcomeFor(over bool, winner int, toMove int) (bool, Outcome) {
+	switch {
+	case !over:
+		return false, Draw
+	case winner == -1:
+		return true, Draw
+	case winner == toMove:
+		return true, Win
+	default:
+		return true, Loss
+	}
+}
+
+// SearchResult is the move found by SearchPosition and what it is based on.
+type SearchResult[M comparable] struct {
+	Move               M
+	Score              int // for the player to move, WinScore minus the plies to a win if it is forced
+	PrincipalVariation []M // the best line of play found, starting with Move
+	Stats              Stats
+}
+
+// SearchPosition runs an alpha-beta search maxDepth plies deep and returns the
+// best move for the player to move. Positions at the depth limit are scored
+// with their Evaluate method. If ctx is done before the search finishes, the
+// best move scored so far, if any, is returned together with the context's
+// error; check the principal variation to tell whether there is one.
+func SearchPosition[M comparable](ctx context.Context, position Position[M], maxDepth int) (SearchResult[M], error) {
+	s := &positionSearch[M]{ctx: ctx, position: position, maxDepth: maxDepth}
+
+	start := time.Now()
+	defer func() { s.stats.Duration = time.Since(start) }()
+
+	var result SearchResult[M]
+	result.Score = -WinScore - 1
+	alpha, beta := -WinScore-1, WinScore+1
+
+	for _, move := range position.Moves() {
+		position.Play(move)
+		score, line, err := s.negamax(1, -beta, -alpha)
+		position.Undo(move)
+
+		if err != nil {
+			result.Stats = s.stats
+			return result, err
+		}
+
+		score = -score
+		if score > result.Score {
+			result.Move, result.Score = move, score
+			result.PrincipalVariation = append([]M{move}, line...)
+			alpha = max(alpha, score)
+		}
+	}
+
+	result.Stats = s.stats
+	return result, nil
+}
+
+// SearchPositionWithin runs SearchPosition one depth at a time, up to
+// maxDepth, until the time budget runs out. It returns the result of the
+// deepest search that completed in time. Running out of time is not an error,
+// but if ctx itself is done the context's error is returned.
+func SearchPositionWithin[M comparable](ctx context.Context, position Position[M], budget time.Duration, maxDepth int) (SearchResult[M], error) {
+	searchCtx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	var best SearchResult[M]
+	for depth := 1; depth <= maxDepth; depth++ {
+		result, err := SearchPosition(searchCtx, position, depth)
+		if err != nil {
+			// Fall back on any legal move if not even one ply could be searched
+			if depth == 1 {
+				best = result
+				if moves := position.Moves(); len(best.PrincipalVariation) == 0 && len(moves) > 0 {
+					best.Move = moves[0]
+					best.PrincipalVariation = moves[:1]
+				}
+			}
+			break
+		}
+		best = result
+
+		// A forced result won't change with more depth
+		if result.Score >= WinScore-depth || result.Score <= -WinScore+depth {
+			break
+		}
+	}
+	return best, ctx.Err()
+}
+
+// AIDepth returns the search depth of an AI difficulty between 0 and 9, for
+// games where it takes levels difficulty levels to gain plies plies of depth.
+// The easiest level searches a single ply.
+func AIDepth(difficulty int, plies int, levels int) int {
+	return 1 + difficulty*plies/levels
+}
+
+// PlayWithin searches for a move for the player to move and plays it. With a
+// time budget the search deepens, up to maxDepth, until the budget runs out,
+// otherwise it searches depth plies. It returns an error if there is no move
+// to play.
+func PlayWithin[M comparable](ctx context.Context, position Position[M], depth int, budget time.Duration, maxDepth int) error {
+	var result SearchResult[M]
+	var err error
+	if budget > 0 {
+		result, err = SearchPositionWithin(ctx, position, budget, maxDepth)
+	} else {
+		result, err = SearchPosition(ctx, position, depth)
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.PrincipalVariation) == 0 {
+		return errors.New("no move to play")
+	}
+
+	position.Play(result.Move)
+	return nil
+}
+
+// positionSearch holds the state shared by every node of a SearchPosition.
+type positionSearch[M comparable] struct {
+	ctx      context.Context
+	position Position[M]
+	maxDepth int
+	stats    Stats
+}
+
+// negamax scores the position for the player to move, depth plies below the
+// root, and returns the line of play that leads to the score.
+func (s *positionSearch[M]) negamax(depth int, alpha int, beta int) (int, []M, error) {
+
+	if err := s.ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	s.stats.Nodes++
+	s.stats.MaxDepth = max(s.stats.MaxDepth, depth)
+
+	if over, outcome := s.position.Outcome(); over {
+		s.stats.LeafEvaluations++
+		return int(outcome) * (WinScore - depth), nil, nil
+	}
+	if depth >= s.maxDepth {
+		s.stats.LeafEvaluations++
+		return s.position.Evaluate(), nil, nil
+	}
+
+	bestScore := -WinScore - 1
+	var bestLine []M
+	for _, move := range s.position.Moves() {
+		s.position.Play(move)
+		score, line, err := s.negamax(depth+1, -beta, -alpha)
+		s.position.Undo(move)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		score = -score
+		if score > bestScore {
+			bestScore = score
+			bestLine = append([]M{move}, line...)
+		}
+
+		alpha = max(alpha, score)
+		if alpha >= beta {
+			s.stats.Cutoffs++
+			break
+		}
+	}
+	return bestScore, bestLine, nil
+}
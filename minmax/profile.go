@@ -0,0 +1,101 @@
+package minmax
+
+import (
+	"context"
+	"math"
+	"math/rand"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+)
+
+// Profile is how an AI player of one difficulty plays: how deep it searches,
+// and how it picks its move from the scores of the root moves.
+type Profile struct {
+	Depth int // plies searched, as for GetBestMove
+
+	// Temperature spreads the choice over the root moves, each picked with a
+	// probability of exp(score/Temperature) relative to the others. The
+	// scores are those of a search with the DefaultEvaluator, so a few
+	// hundred blurs the evaluation while wins and losses stay far apart.
+	// Zero always plays the best move.
+	Temperature float64
+
+	// Mistake is the chance of playing one of the moves that scored worse
+	// than the best, picked at random.
+	Mistake float64
+}
+
+// Profiles are the profiles of the AI difficulties 0 to 9. They were
+// calibrated with the simulation's -calibrate mode, which plays each level
+// against perfect play on the classic board, 300 games as X and 300 as O, and
+// the share of games the level didn't lose is noted after each. Only the
+// hardest level plays perfectly.
+var Profiles = [10]Profile{
+	{Depth: 0, Temperature: 400, Mistake: 0.1},  // 11%
+	{Depth: 1, Temperature: 300, Mistake: 0.43}, // 19%
+	{Depth: 1, Temperature: 300, Mistake: 0.26}, // 31%
+	{Depth: 2, Temperature: 50, Mistake: 0.17},  // 40%
+	{Depth: 2, Temperature: 30, Mistake: 0.15},  // 52%
+	{Depth: 3, Temperature: 30, Mistake: 0.13},  // 60%
+	{Depth: 4, Temperature: 20, Mistake: 0.1},   // 69%
+	{Depth: 5, Temperature: 10, Mistake: 0.1},   // 82%
+	{Depth: 9, Temperature: 0, Mistake: 0.04},   // 92%
+	{Depth: 9, Temperature: 0, Mistake: 0},      // 100%
+}
+
+// ProfileFor returns the profile of an AI difficulty, clamped to 0..9.
+func ProfileFor(difficulty int) Profile {
+	return Profiles[min(max(difficulty, 0), len(Profiles)-1)]
+}
+
+// GetPlacementWithProfile searches the board to the profile's depth, like
+// GetBestPlacement, and then picks the move to play with Choose. The move
+// may not be the best one found, unless the profile never errs.
+func GetPlacementWithProfile(ctx context.Context, board board.Board, profile Profile, playerToken string, options Options) (Move, Stats, error) {
+	move, stats, err := GetBestPlacement(ctx, board, profile.Depth, playerToken, options)
+	if err != nil || len(stats.RootScores) == 0 {
+		return move, stats, err
+	}
+	return profile.Choose(stats.RootScores), stats, nil
+}
+
+// Choose picks a move from the scores of the root moves, which must not be
+// empty. With no mistake and no temperature it is the first best move.
+func (p Profile) Choose(scores []MoveScore) Move {
+	best := scores[0]
+	for _, score := range scores {
+		if score.Score > best.Score {
+			best = score
+		}
+	}
+
+	if p.Mistake > 0 && rand.Float64() < p.Mistake {
+		var worse []Move
+		for _, score := range scores {
+			if score.Score < best.Score {
+				worse = append(worse, score.Move)
+			}
+		}
+		if len(worse) > 0 {
+			return worse[rand.Intn(len(worse))]
+		}
+	}
+	if p.Temperature <= 0 {
+		return best.Move
+	}
+
+	// Softmax, relative to the best score so the weights stay at most 1
+	weights := make([]float64, len(scores))
+	total := 0.0
+	for i, score := range scores {
+		weights[i] = math.Exp(float64(score.Score-best.Score) / p.Temperature)
+		total += weights[i]
+	}
+	pick := rand.Float64() * total
+	for i, weight := range weights {
+		if pick -= weight; pick < 0 {
+			return scores[i].Move
+		}
+	}
+	return best.Move
+}
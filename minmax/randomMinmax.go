@@ -1,103 +1,29 @@
 package minmax
 
 import (
-	"log"
-	"math"
+	"context"
 	"math/rand"
 
 	"github.com/jackmcdermo/tic-tac-toe-/board"
 )
 
 func GetBestMoveWithRandom(board board.Board, maxDepth int, playerToken string) (int, int) {
-	bestRow, bestCol := -1, -1
-	var bestScore int
-
-	bestScore = math.MinInt
-	rs := NewRandomSpot(board)
-	for spot := rs.GetNextOpenMove(board); spot != nil; spot = rs.GetNextOpenMove(board) {
-
-		// Simulate a move for the AI player
-		ok := board.PlaceToken(spot.row, spot.col, playerToken)
-		if !ok {
-			log.Printf("trying to place a token in a non-empty spot")
-			panic("trying to place a token in a non-empty spot")
-		}
-
-		// Call minmax to get the score for the move
-		score := randminmax(board, 0, false, maxDepth, playerToken)
-
-		// Undo the move
-		board.RemoveToken(spot.row, spot.col)
-
-		if score > bestScore {
-			bestScore = score
-			bestRow = spot.row
-			bestCol = spot.col
-		}
-	}
-	return bestRow, bestCol
+	row, col, _ := GetBestMoveWithRandomContext(context.Background(), board, maxDepth, playerToken)
+	return row, col
 }
 
-func randminmax(board board.Board, depth int, isMaximizing bool, maxDepth int, playerToken string) int {
-
-	opponentToken := "X"
-	if playerToken == "X" {
-		opponentToken = "O"
-	}
-
-	if board.CheckWinForPlayer(opponentToken) {
-		return -1
-	} else if board.CheckWinForPlayer(playerToken) {
-		return 1
-	} else if board.CheckTie() || depth == maxDepth {
-		return 0
-	}
-
-	depth += 1
-	if isMaximizing {
-		maxEval := math.MinInt
-		rs := NewRandomSpot(board)
-		for spot := rs.GetNextOpenMove(board); spot != nil; spot = rs.GetNextOpenMove(board) {
-
-			// Simulate a move for the player
-			ok := board.PlaceToken(spot.row, spot.col, playerToken)
-			if !ok {
-				log.Printf("trying to place a token in a non-empty spot")
-				panic("trying to place a token in a non-empty spot")
-			}
-
-			// Recursively call _minmax with the new board state
-			score := randminmax(board, depth, false, maxDepth, playerToken)
-
-			// Undo the move
-			board.RemoveToken(spot.row, spot.col)
-			maxEval = max(maxEval, score)
-		}
-		return maxEval
-
-	} else {
-		minEval := math.MaxInt
-		rs := NewRandomSpot(board)
-
-		for spot := rs.GetNextOpenMove(board); spot != nil; spot = rs.GetNextOpenMove(board) {
-
-			// Simulate a move for the opponent
-			ok := board.PlaceToken(spot.row, spot.col, opponentToken)
-			if !ok {
-				log.Printf("trying to place a token in a non-empty spot")
-				panic("trying to place a token in a non-empty spot")
-			}
-
-			// Recursively call _minmax with the new board state
-			score := randminmax(board, depth, true, maxDepth, playerToken)
-
-			// Undo the move
-			board.RemoveToken(spot.row, spot.col)
-			minEval = min(minEval, score)
+// GetBestMoveWithRandomContext is GetBestMoveWithRandom with cancellation. Like
+// GetBestMoveContext, it returns the best move scored so far and the context's
+// error if ctx is done before the search finishes.
+func GetBestMoveWithRandomContext(ctx context.Context, board board.Board, maxDepth int, playerToken string) (int, int, error) {
+	row, col, _, err := GetBestMoveWithRandomStats(ctx, board, maxDepth, playerToken)
+	return row, col, err
+}
 
-		}
-		return minEval
-	}
+// GetBestMoveWithRandomStats is GetBestMoveWithRandomContext that also reports
+// what the search did.
+func GetBestMoveWithRandomStats(ctx context.Context, board board.Board, maxDepth int, playerToken string) (int, int, Stats, error) {
+	return GetBestMoveWithOptions(ctx, board, maxDepth, playerToken, Options{Random: true})
 }
 
 type randomSpot struct {
@@ -119,24 +45,24 @@ func (rs *randomSpot) GetNextOpenMove(board board.Board) *Spot {
 		return &Spot{rs.startingSpot.row, rs.startingSpot.col}
 	}
 
-	for rs.totalMoves < 9 {
+	for rs.totalMoves < board.Rows()*board.Cols() {
 		// Move over one column
 		rs.currentRow++
 
 		// If we are at the end of the row, move to the next column
 		// and reset the row
-		if rs.currentRow > 2 {
+		if rs.currentRow >= board.Rows() {
 			rs.currentRow = 0
 			rs.currentCol++
 		}
 
 		// If we are at the end of the column, start at the top
-		if rs.currentCol > 2 {
+		if rs.currentCol >= board.Cols() {
 			rs.currentCol = 0
 		}
 
 		rs.totalMoves++
-		if board.GetToken(rs.currentRow, rs.currentCol) == " " {
+		if board.CanPlace(rs.currentRow, rs.currentCol) {
 			return &Spot{rs.currentRow, rs.currentCol}
 		}
 	}
@@ -158,9 +84,9 @@ func NewRandomSpot(board board.Board) randomSpot {
 	totalRandomSpots++
 	// Get all open spots on the board
 	openSpots := make(map[int]spot)
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			if board.GetToken(i, j) == " " {
+	for i := 0; i < board.Rows(); i++ {
+		for j := 0; j < board.Cols(); j++ {
+			if board.CanPlace(i, j) {
 				openSpots[len(openSpots)] = spot{i, j}
 			}
 		}
@@ -0,0 +1,53 @@
+package minmax
+
+import (
+	"fmt"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/game"
+)
+
+// MoveReview compares the perfect-play outcome of the position before a move
+// with the outcome after it, both for the player who made the move.
+type MoveReview struct {
+	Move   game.Move
+	Before Outcome
+	After  Outcome
+}
+
+// Blunder reports whether the move made the result worse for the player.
+func (r MoveReview) Blunder() bool {
+	return r.After < r.Before
+}
+
+// Annotation describes the move and, for a blunder, what it threw away.
+func (r MoveReview) Annotation() string {
+	move := fmt.Sprintf("%s %d,%d", r.Move.Token, r.Move.Row, r.Move.Col)
+	if !r.Blunder() {
+		return fmt.Sprintf("%s: keeps the %s", move, r.After)
+	}
+	return fmt.Sprintf("%s: blunder, turns a %s into a %s", move, r.Before, r.After)
+}
+
+// ReviewGame replays the moves of a game from an empty board and reviews
+// each of them with perfect play. Only the Misere option applies.
+func ReviewGame(history []game.Move, options Options) []MoveReview {
+	b := board.NewBoard()
+	b.InitBoard()
+
+	s := newSolver(options.Misere)
+	var reviews []MoveReview
+	for _, move := range history {
+		opponentToken := "X"
+		if move.Token == "X" {
+			opponentToken = "O"
+		}
+
+		before, _ := s.solve(*b, move.Token, opponentToken)
+		b.PlaceToken(move.Row, move.Col, move.Token)
+		after, _ := s.solve(*b, opponentToken, move.Token)
+
+		reviews = append(reviews, MoveReview{move, before, -after})
+	}
+	return reviews
+}
@@ -0,0 +1,301 @@
+// Package morris implements Three Men's Morris on the tic-tac-toe board. Each
+// player has three pieces, which they place in turn as in tic-tac-toe. Once
+// all six are down, a move slides one of the player's pieces along a line of
+// the board to the next point, which must be empty. The first player to get
+// three in a row wins, and a player who can't move loses.
+//
+// Unlike tic-tac-toe the game can go round in circles, so it is drawn when the
+// same position comes up for the Repetitions-th time, or after MoveLimit moves.
+package morris
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+)
+
+// Pieces is the number of pieces each player has.
+const Pieces = 3
+
+// Repetitions is the number of times the same position, with the same player
+// to move, has to come up for the game to be drawn.
+const Repetitions = 3
+
+// MoveLimit is the number of moves, counting both players', after which the
+// game is drawn.
+const MoveLimit = 60
+
+// lines are the rows, columns and diagonals of the board.
+var lines = [][3]Point{
+	{{0, 0}, {0, 1}, {0, 2}}, {{1, 0}, {1, 1}, {1, 2}}, {{2, 0}, {2, 1}, {2, 2}},
+	{{0, 0}, {1, 0}, {2, 0}}, {{0, 1}, {1, 1}, {2, 1}}, {{0, 2}, {1, 2}, {2, 2}},
+	{{0, 0}, {1, 1}, {2, 2}}, {{0, 2}, {1, 1}, {2, 0}},
+}
+
+// Point is one of the nine points of the board.
+type Point struct {
+	Row int
+	Col int
+}
+
+// Adjacent reports whether a piece can slide between the points, which is
+// when they are next to each other on one of the lines.
+func Adjacent(a Point, b Point) bool {
+	dr, dc := b.Row-a.Row, b.Col-a.Col
+	if max(abs(dr), abs(dc)) != 1 {
+		return false
+	}
+	if dr == 0 || dc == 0 {
+		return true
+	}
+	// The only diagonal lines are the two through the centre
+	return (a.Row == a.Col && b.Row == b.Col) || (a.Row+a.Col == 2 && b.Row+b.Col == 2)
+}
+
+// Kind tells the two kinds of move apart.
+type Kind int
+
+const (
+	Place Kind = iota // put a new piece on an empty point
+	Slide             // move a piece already on the board to an adjacent point
+)
+
+// Move places a piece on To, or slides the piece on From to To.
+type Move struct {
+	Kind Kind
+	From Point // only set for slides
+	To   Point
+}
+
+func (m Move) String() string {
+	if m.Kind == Slide {
+		return fmt.Sprintf("%d,%d-%d,%d", m.From.Row, m.From.Col, m.To.Row, m.To.Col)
+	}
+	return fmt.Sprintf("%d,%d", m.To.Row, m.To.Col)
+}
+
+type Game struct {
+	board   *board.Board
+	toMove  int // index of the player to move, 0 for X
+	history []Move
+	seen    map[string]int // the times each position has come up
+}
+
+func NewGame() *Game {
+	g := &Game{board: board.NewBoard(), seen: make(map[string]int)}
+	g.seen[g.key()]++
+	return g
+}
+
+// key identifies the position for repetition: the pieces and the player to move.
+func (g *Game) key() string {
+	return g.board.Key() + minmax.Tokens[g.toMove]
+}
+
+// GetToken returns the token at the given point.
+func (g *Game) GetToken(p Point) string {
+	return g.board.GetToken(p.Row, p.Col)
+}
+
+// PlayerToMove returns the index of the player to move, 0 for X and 1 for O.
+func (g *Game) PlayerToMove() int {
+	return g.toMove
+}
+
+// Placing reports whether the players are still placing their pieces.
+func (g *Game) Placing() bool {
+	return len(g.history) < 2*Pieces
+}
+
+// Moves returns the legal moves of the player to move: the empty points
+// while placing, and afterwards every slide of one of their pieces.
+func (g *Game) Moves() []Move {
+	if over, _ := g.Result(); over {
+		return nil
+	}
+	if !g.Placing() {
+		return g.slides(minmax.Tokens[g.toMove])
+	}
+
+	var moves []Move
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			if g.board.CanPlace(r, c) {
+				moves = append(moves, Move{Kind: Place, To: Point{r, c}})
+			}
+		}
+	}
+	return moves
+}
+
+// slides returns the moves of the pieces holding token to an adjacent empty point.
+func (g *Game) slides(token string) []Move {
+	var moves []Move
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			from := Point{r, c}
+			if g.GetToken(from) != token {
+				continue
+			}
+			for tr := 0; tr < 3; tr++ {
+				for tc := 0; tc < 3; tc++ {
+					to := Point{tr, tc}
+					if Adjacent(from, to) && g.board.CanPlace(tr, tc) {
+						moves = append(moves, Move{Kind: Slide, From: from, To: to})
+					}
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// Play makes a move for the player to move. The move must be legal.
+func (g *Game) Play(move Move) {
+	if move.Kind == Slide {
+		g.board.RemoveToken(move.From.Row, move.From.Col)
+	}
+	g.board.PlaceToken(move.To.Row, move.To.Col, minmax.Tokens[g.toMove])
+	g.history = append(g.history, move)
+	g.toMove = 1 - g.toMove
+	g.seen[g.key()]++
+}
+
+// Undo takes back the last move.
+func (g *Game) Undo(move Move) {
+	g.seen[g.key()]--
+	g.toMove = 1 - g.toMove
+	g.history = g.history[:len(g.history)-1]
+	g.board.RemoveToken(move.To.Row, move.To.Col)
+	if move.Kind == Slide {
+		g.board.PlaceToken(move.From.Row, move.From.Col, minmax.Tokens[g.toMove])
+	}
+}
+
+// Outcome reports whether the game is over and, if so, the result for the
+// player to move.
+func (g *Game) Outcome() (bool, minmax.Outcome) {
+	over, winner := g.Result()
+	return minmax.OutcomeFor(over, winner, g.toMove)
+}
+
+// Result reports whether the game is over and the index of the winner, or
+// -1 for a draw.
+func (g *Game) Result() (bool, int) {
+	// Only the player who just moved can have three in a row
+	if g.board.CheckWin() {
+		return true, 1 - g.toMove
+	}
+	if !g.Placing() && len(g.slides(minmax.Tokens[g.toMove])) == 0 {
+		return true, 1 - g.toMove
+	}
+	if g.seen[g.key()] >= Repetitions || len(g.history) >= MoveLimit {
+		return true, -1
+	}
+	return false, -1
+}
+
+// Evaluate scores the position for the player to move by the lines each
+// player is one piece short of, and by how many slides each has.
+func (g *Game) Evaluate() int {
+	me, opponent := minmax.Tokens[g.toMove], minmax.Tokens[1-g.toMove]
+	return 10*(g.threats(me)-g.threats(opponent)) + len(g.slides(me)) - len(g.slides(opponent))
+}
+
+// threats counts the lines with two pieces holding token and an empty point.
+func (g *Game) threats(token string) int {
+	threats := 0
+	for _, l := range lines {
+		pieces, empty := 0, 0
+		for _, p := range l {
+			switch g.GetToken(p) {
+			case token:
+				pieces++
+			case " ":
+				empty++
+			}
+		}
+		if pieces == 2 && empty == 1 {
+			threats++
+		}
+	}
+	return threats
+}
+
+// PlayAIMove plays the move minmax.PlayWithin finds for the AI difficulty,
+// which gains a ply every level. A time budget searches up to the moves left
+// before the game is drawn.
+func (g *Game) PlayAIMove(ctx context.Context, difficulty int, budget time.Duration) error {
+	return minmax.PlayWithin(ctx, g, minmax.AIDepth(difficulty, 1, 1), budget, MoveLimit-len(g.history))
+}
+
+// MoveFormat describes the move input expected from the player to move.
+func (g *Game) MoveFormat() string {
+	if g.Placing() {
+		return "row,col"
+	}
+	return "from row,col-to row,col"
+}
+
+// ParseMove parses a placement in the format "row,col", or once all pieces
+// are placed, a slide in the format "row,col-row,col". Returns an error if the
+// move is not in the expected format or is off the board.
+func (g *Game) ParseMove(input string) (Move, error) {
+	points := strings.Split(strings.TrimSpace(input), "-")
+	switch {
+	case g.Placing() && len(points) != 1:
+		return Move{}, fmt.Errorf("Place a piece with row,col until all %d are down.", 2*Pieces)
+	case !g.Placing() && len(points) != 2:
+		return Move{}, fmt.Errorf("All pieces are down, slide one with row,col-row,col.")
+	}
+
+	var move Move
+	for i, p := range points {
+		var point Point
+		if _, err := fmt.Sscanf(strings.TrimSpace(p), "%d,%d", &point.Row, &point.Col); err != nil {
+			return Move{}, err
+		}
+		if !g.board.InBounds(point.Row, point.Col) {
+			return Move{}, fmt.Errorf("invalid move")
+		}
+		if i == 0 && len(points) == 2 {
+			move.Kind, move.From = Slide, point
+		} else {
+			move.To = point
+		}
+	}
+	return move, nil
+}
+
+// PlayMove parses a move and plays it for the player to move.
+func (g *Game) PlayMove(input string) error {
+	move, err := g.ParseMove(input)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(g.Moves(), move) {
+		if move.Kind == Place {
+			return fmt.Errorf("That space is already occupied. Please try again.")
+		}
+		return fmt.Errorf("Slide one of your pieces along a line to the empty point next to it. Please try again.")
+	}
+	g.Play(move)
+	return nil
+}
+
+// PrintBoard prints the board.
+func (g *Game) PrintBoard() {
+	g.board.PrintBoard()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
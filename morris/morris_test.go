@@ -0,0 +1,129 @@
+package morris
+
+import (
+	"context"
+	"testing"
+)
+
+// place plays placements at the points, for each player in turn.
+func place(g *Game, points ...Point) {
+	for _, p := range points {
+		g.Play(Move{Kind: Place, To: p})
+	}
+}
+
+func TestAdjacent(t *testing.T) {
+	tests := []struct {
+		a, b     Point
+		expected bool
+	}{
+		{Point{0, 0}, Point{0, 1}, true},
+		{Point{0, 0}, Point{1, 1}, true},
+		{Point{1, 1}, Point{2, 0}, true},
+		{Point{0, 1}, Point{1, 0}, false}, // no line joins the middles of two sides
+		{Point{0, 0}, Point{0, 2}, false},
+		{Point{1, 1}, Point{1, 1}, false},
+	}
+	for _, tt := range tests {
+		if got := Adjacent(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Adjacent(%v, %v): expected %v, got %v", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestSlidesAfterPlacing(t *testing.T) {
+	g := NewGame()
+	place(g, Point{0, 0}, Point{0, 1}, Point{1, 0}, Point{1, 1}, Point{2, 1}, Point{2, 2})
+	if g.Placing() {
+		t.Fatal("expected the placing to be over")
+	}
+
+	// X holds 0,0, 1,0 and 2,1, and the empty points are 0,2, 1,2 and 2,0
+	expected := []Move{
+		{Kind: Slide, From: Point{1, 0}, To: Point{2, 0}},
+		{Kind: Slide, From: Point{2, 1}, To: Point{2, 0}},
+	}
+	moves := g.Moves()
+	if len(moves) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, moves)
+	}
+	for i := range expected {
+		if moves[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], moves[i])
+		}
+	}
+}
+
+func TestRepetitionDraws(t *testing.T) {
+	g := NewGame()
+	place(g, Point{0, 0}, Point{0, 1}, Point{1, 0}, Point{1, 1}, Point{2, 1}, Point{2, 2})
+
+	// Both players slide a piece out and back, twice
+	shuffle := []Move{
+		{Kind: Slide, From: Point{1, 0}, To: Point{2, 0}},
+		{Kind: Slide, From: Point{2, 2}, To: Point{1, 2}},
+		{Kind: Slide, From: Point{2, 0}, To: Point{1, 0}},
+		{Kind: Slide, From: Point{1, 2}, To: Point{2, 2}},
+	}
+	for i := 0; i < Repetitions-1; i++ {
+		for _, move := range shuffle {
+			if over, _ := g.Result(); over {
+				t.Fatalf("game over early after %d moves", len(g.history))
+			}
+			g.Play(move)
+		}
+	}
+
+	if over, winner := g.Result(); !over || winner != -1 {
+		t.Errorf("expected a draw by repetition, got %v %d", over, winner)
+	}
+
+	g.Undo(shuffle[len(shuffle)-1])
+	if over, _ := g.Result(); over {
+		t.Error("undo should take back the repetition")
+	}
+}
+
+func TestPlayMove(t *testing.T) {
+	g := NewGame()
+	if err := g.PlayMove("1,1-0,0"); err == nil {
+		t.Error("expected slides to be rejected while placing")
+	}
+	if err := g.PlayMove("1,1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.PlayMove("1,1"); err == nil {
+		t.Error("expected an occupied point to be rejected")
+	}
+
+	place(g, Point{0, 0}, Point{0, 1}, Point{2, 1}, Point{1, 0}, Point{2, 2})
+	if err := g.PlayMove("0,1"); err == nil {
+		t.Error("expected placements to be rejected once all pieces are down")
+	}
+	if err := g.PlayMove("2,2-1,2"); err == nil {
+		t.Error("expected sliding the opponent's piece to be rejected")
+	}
+	if err := g.PlayMove("1,0-1,2"); err == nil {
+		t.Error("expected a jump to be rejected")
+	}
+	if err := g.PlayMove("0,1-1,2"); err == nil {
+		t.Error("expected a slide to a point off the lines to be rejected")
+	}
+	if err := g.PlayMove("0,1-0,2"); err != nil {
+		t.Errorf("expected the slide to be played, got %v", err)
+	}
+}
+
+func TestAIWinsBySliding(t *testing.T) {
+	g := NewGame()
+	// O to move can slide 1,2 down to 2,2 to finish the bottom row
+	place(g, Point{0, 0}, Point{2, 0}, Point{1, 1}, Point{2, 1}, Point{0, 1}, Point{1, 2})
+	g.Play(Move{Kind: Slide, From: Point{1, 1}, To: Point{1, 0}})
+
+	if err := g.PlayAIMove(context.Background(), 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if over, winner := g.Result(); !over || winner != 1 {
+		t.Errorf("expected O to win, got %v %d", over, winner)
+	}
+}
@@ -0,0 +1,290 @@
+// Package notakto implements Notakto, tic-tac-toe where both players place X
+// on one or more boards. A board with three in a row is dead and can't be
+// played on any more, and the player who kills the last board loses.
+//
+// There are no draws, and the positions are few enough that the hardest AI
+// solves the game exactly. Since the boards are independent, a position is
+// stored by the canonical forms of its live boards, in any order.
+package notakto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+)
+
+// token is the only token, placed by both players.
+const token = "X"
+
+// Move is a space on one of the boards.
+type Move struct {
+	Board int
+	Row   int
+	Col   int
+}
+
+type Game struct {
+	boards  []*board.Board
+	toMove  int // index of the player to move
+	history []Move
+	solved  map[string]bool // whether the player to move wins, by position key
+}
+
+// NewGame returns a game on the given number of boards.
+func NewGame(boards int) *Game {
+	g := &Game{solved: make(map[string]bool)}
+	for i := 0; i < boards; i++ {
+		g.boards = append(g.boards, board.NewBoard())
+	}
+	return g
+}
+
+// PlayerToMove returns the index of the player to move, 0 for the player who
+// moved first.
+func (g *Game) PlayerToMove() int {
+	return g.toMove
+}
+
+// Dead reports whether the board has three in a row.
+func (g *Game) Dead(b int) bool {
+	return g.boards[b].CheckWin()
+}
+
+// liveBoards returns the boards that can still be played on.
+func (g *Game) liveBoards() []int {
+	var live []int
+	for b := range g.boards {
+		if !g.Dead(b) {
+			live = append(live, b)
+		}
+	}
+	return live
+}
+
+// IsLegal reports whether the move is on an open space of a live board.
+func (g *Game) IsLegal(move Move) bool {
+	if move.Board < 0 || move.Board >= len(g.boards) || g.Dead(move.Board) {
+		return false
+	}
+	return g.boards[move.Board].CanPlace(move.Row, move.Col)
+}
+
+// Moves returns the open spaces of the live boards.
+func (g *Game) Moves() []Move {
+	var moves []Move
+	for _, b := range g.liveBoards() {
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				if g.boards[b].CanPlace(r, c) {
+					moves = append(moves, Move{b, r, c})
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// Play places an X for the player to move. The move must be legal.
+func (g *Game) Play(move Move) {
+	g.boards[move.Board].PlaceToken(move.Row, move.Col, token)
+	g.history = append(g.history, move)
+	g.toMove = 1 - g.toMove
+}
+
+// Undo takes back the last move.
+func (g *Game) Undo(move Move) {
+	g.boards[move.Board].RemoveToken(move.Row, move.Col)
+	g.history = g.history[:len(g.history)-1]
+	g.toMove = 1 - g.toMove
+}
+
+// Outcome reports whether the game is over and, if so, the result for the
+// player to move, who wins once the opponent has killed the last board.
+func (g *Game) Outcome() (bool, minmax.Outcome) {
+	if over, _ := g.Result(); over {
+		return true, minmax.Win
+	}
+	return false, minmax.Draw
+}
+
+// Result reports whether the game is over and the index of the winner.
+// Notakto has no draws, so the winner is never -1.
+func (g *Game) Result() (bool, int) {
+	if len(g.liveBoards()) > 0 {
+		return false, -1
+	}
+	return true, g.toMove
+}
+
+// Evaluate scores every unsolved position as even. Short of solving it, there
+// is no simple way to tell who is ahead.
+func (g *Game) Evaluate() int {
+	return 0
+}
+
+// key identifies the position for the solver. Dead boards don't matter, and
+// live boards count the same in any order and any orientation.
+func (g *Game) key() string {
+	var keys []string
+	for _, b := range g.liveBoards() {
+		canonical, _ := g.boards[b].Canonical()
+		keys = append(keys, canonical.Key())
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "|")
+}
+
+// wins reports whether the player to move wins with perfect play.
+func (g *Game) wins(ctx context.Context) (bool, error) {
+	if over, _ := g.Result(); over {
+		return true, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	key := g.key()
+	if won, ok := g.solved[key]; ok {
+		return won, nil
+	}
+
+	won := false
+	for _, move := range g.Moves() {
+		g.Play(move)
+		opponentWins, err := g.wins(ctx)
+		g.Undo(move)
+		if err != nil {
+			return false, err
+		}
+		if !opponentWins {
+			won = true
+			break
+		}
+	}
+	g.solved[key] = won
+	return won, nil
+}
+
+// BestMove solves the position and returns a winning move for the player to
+// move, or, in a lost position, the move that kills a board last.
+func (g *Game) BestMove(ctx context.Context) (Move, error) {
+	moves := g.Moves()
+	if len(moves) == 0 {
+		return Move{}, fmt.Errorf("no move to play")
+	}
+
+	best, bestKills := moves[0], true
+	for _, move := range moves {
+		g.Play(move)
+		opponentWins, err := g.wins(ctx)
+		kills := g.Dead(move.Board)
+		g.Undo(move)
+		if err != nil {
+			return Move{}, err
+		}
+
+		if !opponentWins {
+			return move, nil
+		}
+		if bestKills && !kills {
+			best, bestKills = move, false
+		}
+	}
+	return best, nil
+}
+
+// PlayAIMove finds a move for the player to move and plays it. The hardest
+// difficulty solves the position, and lower difficulties search with
+// minmax.PlayWithin, gaining a ply every three levels. A time budget gives
+// half its time to solving, and if that doesn't finish, deepens a search in
+// the other half like the other variants.
+func (g *Game) PlayAIMove(ctx context.Context, difficulty int, budget time.Duration) error {
+	if budget == 0 && difficulty < 9 {
+		return minmax.PlayWithin(ctx, g, minmax.AIDepth(difficulty, 1, 3), 0, 0)
+	}
+
+	solveCtx := ctx
+	if budget > 0 {
+		var cancel context.CancelFunc
+		solveCtx, cancel = context.WithTimeout(ctx, budget/2)
+		defer cancel()
+	}
+	move, err := g.BestMove(solveCtx)
+	if budget > 0 && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return minmax.PlayWithin(ctx, g, 0, budget-budget/2, 9*len(g.boards))
+	}
+	if err != nil {
+		return err
+	}
+
+	g.Play(move)
+	return nil
+}
+
+// MoveFormat describes the move input expected from the player to move.
+func (g *Game) MoveFormat() string {
+	if live := g.liveBoards(); len(live) == 1 {
+		return fmt.Sprintf("row,col on board %d", live[0])
+	}
+	return "board,row,col"
+}
+
+// PlayMove parses a move in the MoveFormat and plays it for the player to move.
+func (g *Game) PlayMove(input string) error {
+	input = strings.TrimSpace(input)
+
+	var move Move
+	if _, err := fmt.Sscanf(input, "%d,%d,%d", &move.Board, &move.Row, &move.Col); err != nil {
+		// With one board left the board may be left out
+		live := g.liveBoards()
+		if len(live) != 1 {
+			return err
+		}
+		move.Board = live[0]
+		if _, err := fmt.Sscanf(input, "%d,%d", &move.Row, &move.Col); err != nil {
+			return err
+		}
+	}
+
+	if !g.IsLegal(move) {
+		return fmt.Errorf("invalid move")
+	}
+	g.Play(move)
+	return nil
+}
+
+// PrintBoard prints the boards side by side, marking the dead ones.
+func (g *Game) PrintBoard() {
+	var header, columns, line []string
+	for b := range g.boards {
+		label := fmt.Sprintf("board %d", b)
+		if g.Dead(b) {
+			label = "dead"
+		}
+		header = append(header, fmt.Sprintf("%-7s", label))
+		columns = append(columns, "  0|1|2")
+		line = append(line, "  -----")
+	}
+
+	fmt.Println("")
+	fmt.Printf("   %s\n", strings.Join(header, "   "))
+	fmt.Printf("   %s\n", strings.Join(columns, "   "))
+	for r := 0; r < 3; r++ {
+		if r > 0 {
+			fmt.Printf("   %s\n", strings.Join(line, "   "))
+		}
+
+		var rows []string
+		for _, b := range g.boards {
+			rows = append(rows, fmt.Sprintf("%d %s|%s|%s", r, b.GetToken(r, 0), b.GetToken(r, 1), b.GetToken(r, 2)))
+		}
+		fmt.Printf("   %s\n", strings.Join(rows, "   "))
+	}
+	fmt.Println("")
+}
@@ -0,0 +1,94 @@
+package notakto
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFirstPlayerWins(t *testing.T) {
+	// Known results: the first player wins on one or three boards and loses
+	// on two
+	tests := []struct {
+		boards int
+		wins   bool
+	}{
+		{1, true},
+		{2, false},
+		{3, true},
+	}
+	for _, tt := range tests {
+		g := NewGame(tt.boards)
+		won, err := g.wins(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if won != tt.wins {
+			t.Errorf("%d boards: expected the first player to win to be %v", tt.boards, tt.wins)
+		}
+	}
+}
+
+func TestKillingTheLastBoardLoses(t *testing.T) {
+	g := NewGame(2)
+	for _, move := range []Move{{0, 0, 0}, {0, 0, 1}, {0, 0, 2}, {1, 1, 1}, {1, 0, 0}} {
+		g.Play(move)
+	}
+	if over, _ := g.Result(); over {
+		t.Fatal("expected the game to go on while board 1 is live")
+	}
+
+	g.Play(Move{1, 2, 2})
+	if over, winner := g.Result(); !over || winner != 0 {
+		t.Errorf("expected the player who didn't kill the last board to win, got %v %d", over, winner)
+	}
+}
+
+func TestPlayMove(t *testing.T) {
+	g := NewGame(2)
+	if err := g.PlayMove("1,1,1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.PlayMove("1,1,1\n"); err == nil {
+		t.Error("expected an error for an occupied space")
+	}
+	if err := g.PlayMove("1,1\n"); err == nil {
+		t.Error("expected the board to be required with two live boards")
+	}
+
+	for _, move := range []Move{{0, 0, 0}, {0, 0, 1}, {0, 0, 2}} {
+		g.Play(move)
+	}
+	if err := g.PlayMove("0,1,1\n"); err == nil {
+		t.Error("expected an error for a dead board")
+	}
+	if err := g.PlayMove("0,0\n"); err != nil {
+		t.Errorf("expected the board to be optional with one live board, got %v", err)
+	}
+}
+
+func TestAIWinsFromTheStart(t *testing.T) {
+	g := NewGame(1)
+	for over, _ := g.Result(); !over; over, _ = g.Result() {
+		difficulty := 9
+		if g.PlayerToMove() == 1 {
+			difficulty = 0
+		}
+		if err := g.PlayAIMove(context.Background(), difficulty, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, winner := g.Result(); winner != 0 {
+		t.Errorf("expected the perfect first player to win, got %d", winner)
+	}
+}
+
+func TestAIPlaysWhenTheSolveRunsOutOfTime(t *testing.T) {
+	g := NewGame(3)
+	if err := g.PlayAIMove(context.Background(), 9, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.history) != 1 {
+		t.Errorf("expected the AI to play a move, got %d", len(g.history))
+	}
+}
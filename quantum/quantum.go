@@ -0,0 +1,444 @@
+// Package quantum implements Quantum tic-tac-toe. Each move puts a spooky
+// mark, numbered by the move, in two squares at once, and the marks entangle
+// the squares they share. X places the odd numbered marks and O the even
+// ones. When a mark closes a cycle of entangled squares, the other player
+// picks which of its two squares it collapses into. That settles the mark as
+// a classical one, and every other spooky mark in the square is pushed into
+// its other square, and so on through the cycle.
+//
+// Three classical marks of a player in a row win. When a collapse gives both
+// players a line, the line whose highest mark came first scores a full point
+// and the other half a point.
+package quantum
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+)
+
+// lines are the rows, columns and diagonals, by square. Square s is at row
+// s/3 and column s%3.
+var lines = [][3]int{
+	{0, 1, 2}, {3, 4, 5}, {6, 7, 8},
+	{0, 3, 6}, {1, 4, 7}, {2, 5, 8},
+	{0, 4, 8}, {2, 4, 6},
+}
+
+// mark is the mark placed by a move, in two squares while it is spooky and
+// in the square it collapsed into once it is classical.
+type mark struct {
+	squares [2]int
+	at      int // the square of the classical mark, -1 while it is spooky
+}
+
+// Move collapses the cycle closed by the last mark, if there is one, and
+// places a spooky mark. Squares are numbered 0 to 8, row by row.
+type Move struct {
+	Collapse int // the square the last mark collapses into, -1 without a cycle
+
+	// A and B are the squares of the new mark. They are the same when the
+	// last open square gets a classical mark, and -1 when the collapse
+	// ended the game.
+	A int
+	B int
+}
+
+// state is what Undo restores.
+type state struct {
+	marks   []mark
+	pending bool
+}
+
+type Game struct {
+	state
+	toMove  int // index of the player to move, 0 for X
+	history []Move
+	saved   []state // the state before each move, for Undo
+	choice  int     // the collapse picked by a human who hasn't placed their mark yet, -1 if none
+}
+
+func NewGame() *Game {
+	return &Game{choice: -1}
+}
+
+// PlayerToMove returns the index of the player to move, 0 for X and 1 for O.
+func (g *Game) PlayerToMove() int {
+	return g.toMove
+}
+
+// MustCollapse reports whether the last mark closed a cycle, which the player
+// to move has to collapse before placing their own.
+func (g *Game) MustCollapse() bool {
+	return g.pending && g.choice == -1
+}
+
+// owner returns the index of the player who placed mark i.
+func owner(i int) int {
+	return i % 2
+}
+
+// classical returns the index of the mark that is classical in each square,
+// or -1 where there is none.
+func (g *Game) classical() [9]int {
+	squares := [9]int{-1, -1, -1, -1, -1, -1, -1, -1, -1}
+	for i, m := range g.marks {
+		if m.at != -1 {
+			squares[m.at] = i
+		}
+	}
+	return squares
+}
+
+// openSquares returns the squares without a classical mark.
+func (g *Game) openSquares() []int {
+	var open []int
+	for s, m := range g.classical() {
+		if m == -1 {
+			open = append(open, s)
+		}
+	}
+	return open
+}
+
+// save pushes the state for Undo. The marks are copied, as collapsing
+// changes them in place.
+func (g *Game) save() {
+	g.saved = append(g.saved, state{append([]mark(nil), g.marks...), g.pending})
+}
+
+// restore pops the state saved last.
+func (g *Game) restore() {
+	g.state = g.saved[len(g.saved)-1]
+	g.saved = g.saved[:len(g.saved)-1]
+}
+
+// connected reports whether spooky marks link the two squares.
+func (g *Game) connected(from int, to int) bool {
+	var seen [9]bool
+	seen[from] = true
+	queue := []int{from}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if s == to {
+			return true
+		}
+		for _, m := range g.marks {
+			if m.at != -1 || (m.squares[0] != s && m.squares[1] != s) {
+				continue
+			}
+			next := m.squares[0] + m.squares[1] - s
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+// place adds a mark in squares a and b, a classical one if they are the same.
+func (g *Game) place(a int, b int) {
+	if a == b {
+		g.marks = append(g.marks, mark{[2]int{a, b}, a})
+		return
+	}
+	g.pending = g.connected(a, b)
+	g.marks = append(g.marks, mark{[2]int{a, b}, -1})
+}
+
+// collapse settles the last mark in square s, one of its two, and with it
+// every spooky mark that shares a square with a settled one.
+func (g *Game) collapse(s int) {
+	g.pending = false
+	g.settle(len(g.marks)-1, s)
+}
+
+func (g *Game) settle(i int, s int) {
+	g.marks[i].at = s
+	for j, m := range g.marks {
+		if m.at == -1 && (m.squares[0] == s || m.squares[1] == s) {
+			g.settle(j, m.squares[0]+m.squares[1]-s)
+		}
+	}
+}
+
+// Moves returns the legal moves of the player to move: each collapse of the
+// cycle, if there is one, followed by every way of placing a mark.
+func (g *Game) Moves() []Move {
+	if over, _ := g.Result(); over {
+		return nil
+	}
+	if !g.pending {
+		return g.placements(-1)
+	}
+
+	var moves []Move
+	for _, s := range g.marks[len(g.marks)-1].squares {
+		g.save()
+		g.collapse(s)
+		if over, _ := g.Result(); over {
+			moves = append(moves, Move{Collapse: s, A: -1, B: -1})
+		} else {
+			moves = append(moves, g.placements(s)...)
+		}
+		g.restore()
+	}
+	return moves
+}
+
+// placements returns the moves that collapse into square c and then place a
+// mark, on two open squares or, with only one left, on that one.
+func (g *Game) placements(c int) []Move {
+	open := g.openSquares()
+	if len(open) == 1 {
+		return []Move{{Collapse: c, A: open[0], B: open[0]}}
+	}
+
+	var moves []Move
+	for i, a := range open {
+		for _, b := range open[i+1:] {
+			moves = append(moves, Move{Collapse: c, A: a, B: b})
+		}
+	}
+	return moves
+}
+
+// Play makes a move for the player to move. The move must be legal.
+func (g *Game) Play(move Move) {
+	g.save()
+	if move.Collapse != -1 {
+		g.collapse(move.Collapse)
+	}
+	g.finish(move)
+}
+
+// finish places the move's mark, if it has one, and passes the turn.
+func (g *Game) finish(move Move) {
+	if move.A != -1 {
+		g.place(move.A, move.B)
+	}
+	g.history = append(g.history, move)
+	g.toMove = 1 - g.toMove
+	g.choice = -1
+}
+
+// Undo takes back the last move.
+func (g *Game) Undo(move Move) {
+	g.restore()
+	g.history = g.history[:len(g.history)-1]
+	g.toMove = 1 - g.toMove
+}
+
+// Scores returns the points of each player, indexed by player. Only a game
+// that is over has any.
+func (g *Game) Scores() [2]float64 {
+	// first holds the highest mark of each player's earliest line
+	var scores [2]float64
+	first := [2]int{-1, -1}
+	squares := g.classical()
+	for _, l := range lines {
+		m := [3]int{squares[l[0]], squares[l[1]], squares[l[2]]}
+		if m[0] == -1 || m[1] == -1 || m[2] == -1 {
+			continue
+		}
+		p := owner(m[0])
+		if owner(m[1]) != p || owner(m[2]) != p {
+			continue
+		}
+
+		scores[p]++
+		last := max(m[0], m[1], m[2])
+		if first[p] == -1 || last < first[p] {
+			first[p] = last
+		}
+	}
+
+	// With lines for both, the one completed later counts for half a point
+	if first[0] != -1 && first[1] != -1 {
+		if first[0] < first[1] {
+			scores = [2]float64{1, 0.5}
+		} else {
+			scores = [2]float64{0.5, 1}
+		}
+	}
+	return scores
+}
+
+// Result reports whether the game is over and the index of the winner, or
+// -1 for a draw. It is over once a player has a line or every square holds a
+// classical mark.
+func (g *Game) Result() (bool, int) {
+	scores := g.Scores()
+	switch {
+	case scores[0] > scores[1]:
+		return true, 0
+	case scores[1] > scores[0]:
+		return true, 1
+	}
+	return len(g.openSquares()) == 0, -1
+}
+
+// Outcome reports whether the game is over and, if so, the result for the
+// player to move.
+func (g *Game) Outcome() (bool, minmax.Outcome) {
+	over, winner := g.Result()
+	return minmax.OutcomeFor(over, winner, g.toMove)
+}
+
+// Evaluate scores the position for the player to move by the lines that only
+// one player has marks on. A classical mark counts for more than a spooky
+// one, which may still end up elsewhere.
+func (g *Game) Evaluate() int {
+	squares := g.classical()
+	score := 0
+	for _, l := range lines {
+		var counts [2]int
+		var blocked [2]bool
+		for _, s := range l {
+			if m := squares[s]; m != -1 {
+				counts[owner(m)] += 3
+				blocked[1-owner(m)] = true
+				continue
+			}
+			for i, m := range g.marks {
+				if m.at == -1 && (m.squares[0] == s || m.squares[1] == s) {
+					counts[owner(i)]++
+				}
+			}
+		}
+		for p := range counts {
+			if blocked[p] {
+				continue
+			}
+			if p == g.toMove {
+				score += counts[p]
+			} else {
+				score -= counts[p]
+			}
+		}
+	}
+	return score
+}
+
+// PlayAIMove plays the move minmax.PlayWithin finds for the AI difficulty,
+// which gains a ply every two levels. A time budget searches up to twice the
+// number of lines.
+func (g *Game) PlayAIMove(ctx context.Context, difficulty int, budget time.Duration) error {
+	return minmax.PlayWithin(ctx, g, minmax.AIDepth(difficulty, 1, 2), budget, 2*len(lines))
+}
+
+// MoveFormat describes the move input expected from the player to move.
+func (g *Game) MoveFormat() string {
+	switch {
+	case g.MustCollapse():
+		last := g.marks[len(g.marks)-1]
+		return fmt.Sprintf("the square %s%d collapses into, %s or %s", strings.ToLower(minmax.Tokens[owner(len(g.marks)-1)]), len(g.marks), squareName(last.squares[0]), squareName(last.squares[1]))
+	case len(g.openSquares()) == 1:
+		return fmt.Sprintf("%s, the last open square", squareName(g.openSquares()[0]))
+	}
+	return "row,col row,col"
+}
+
+func squareName(s int) string {
+	return fmt.Sprintf("%d,%d", s/3, s%3)
+}
+
+// parseSquare parses a square in the format "row,col".
+func parseSquare(input string) (int, error) {
+	var row, col int
+	if _, err := fmt.Sscanf(strings.TrimSpace(input), "%d,%d", &row, &col); err != nil {
+		return 0, err
+	}
+	if row < 0 || row > 2 || col < 0 || col > 2 {
+		return 0, fmt.Errorf("invalid move")
+	}
+	return row*3 + col, nil
+}
+
+// PlayMove parses a move in the MoveFormat and plays it for the player to
+// move. A collapse and the mark that follows it are entered one at a time,
+// and the board shows the collapse in between.
+func (g *Game) PlayMove(input string) error {
+	if g.MustCollapse() {
+		s, err := parseSquare(input)
+		if err != nil {
+			return err
+		}
+		if last := g.marks[len(g.marks)-1]; s != last.squares[0] && s != last.squares[1] {
+			return fmt.Errorf("The mark can only collapse into one of its two squares.")
+		}
+
+		g.save()
+		g.collapse(s)
+		g.choice = s
+		if over, _ := g.Result(); over {
+			g.finish(Move{Collapse: s, A: -1, B: -1})
+		}
+		return nil
+	}
+
+	var move Move
+	var err error
+	fields := strings.Fields(input)
+	open := g.openSquares()
+	switch {
+	case len(open) == 1 && len(fields) == 1:
+		move.A, err = parseSquare(fields[0])
+		move.B = move.A
+	case len(fields) == 2:
+		if move.A, err = parseSquare(fields[0]); err == nil {
+			move.B, err = parseSquare(fields[1])
+		}
+	default:
+		err = fmt.Errorf("Please enter the move as %s.", g.MoveFormat())
+	}
+	if err != nil {
+		return err
+	}
+
+	valid := false
+	for _, m := range g.placements(-1) {
+		if (m.A == move.A && m.B == move.B) || (m.A == move.B && m.B == move.A) {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("Place the mark in two different squares without a classical mark. Please try again.")
+	}
+
+	move.Collapse = g.choice
+	if g.choice == -1 {
+		g.save()
+	}
+	g.finish(move)
+	return nil
+}
+
+// PrintBoard prints the classical marks in capitals, and the spooky marks in
+// lower case in both of their squares, each with the number of its move.
+func (g *Game) PrintBoard() {
+	labels := make([][]string, 3)
+	for r := range labels {
+		labels[r] = make([]string, 3)
+	}
+	for i, m := range g.marks {
+		token := fmt.Sprintf("%s%d", minmax.Tokens[owner(i)], i+1)
+		if m.at != -1 {
+			labels[m.at/3][m.at%3] = token
+			continue
+		}
+		for _, s := range m.squares {
+			if labels[s/3][s%3] != "" {
+				labels[s/3][s%3] += " "
+			}
+			labels[s/3][s%3] += strings.ToLower(token)
+		}
+	}
+	board.NewBoard().PrintBoardWithLabels(labels)
+}
@@ -0,0 +1,130 @@
+package quantum
+
+import (
+	"context"
+	"testing"
+)
+
+// entangle plays X1 in 0,1, O2 in 1,2 and X3 in 0,2, which closes a cycle.
+func entangle(g *Game) {
+	g.Play(Move{Collapse: -1, A: 0, B: 1})
+	g.Play(Move{Collapse: -1, A: 1, B: 2})
+	g.Play(Move{Collapse: -1, A: 0, B: 2})
+}
+
+func TestCollapse(t *testing.T) {
+	g := NewGame()
+	entangle(g)
+	if !g.MustCollapse() {
+		t.Fatal("expected X3 to close a cycle")
+	}
+
+	// Two collapses, each followed by a mark in two of the six open squares
+	if moves := g.Moves(); len(moves) != 2*15 {
+		t.Errorf("expected %d moves, got %d", 2*15, len(moves))
+	}
+
+	// X3 in 0 pushes X1 into 1, which pushes O2 into 2
+	g.Play(Move{Collapse: 0, A: 3, B: 4})
+	if squares := g.classical(); squares[0] != 2 || squares[1] != 0 || squares[2] != 1 {
+		t.Errorf("expected X3, X1 and O2 in the top row, got %v", squares[:3])
+	}
+	if g.MustCollapse() {
+		t.Error("expected the cycle to be collapsed")
+	}
+
+	g.Undo(g.history[len(g.history)-1])
+	if !g.MustCollapse() || g.classical() != [9]int{-1, -1, -1, -1, -1, -1, -1, -1, -1} {
+		t.Error("undo should take back the collapse")
+	}
+}
+
+func TestScores(t *testing.T) {
+	tests := []struct {
+		name     string
+		at       []int // the square of each mark, by move
+		expected [2]float64
+	}{
+		{
+			name:     "X line",
+			at:       []int{0, 3, 1, 4, 2},
+			expected: [2]float64{1, 0},
+		},
+		{
+			// X completes the top row with X5, O the middle row with O6
+			name:     "Both lines, X first",
+			at:       []int{0, 3, 1, 4, 2, 5},
+			expected: [2]float64{1, 0.5},
+		},
+		{
+			// O's middle row ends with O6 and X's top row with X7
+			name:     "Both lines, O first",
+			at:       []int{0, 3, 1, 4, 6, 5, 2},
+			expected: [2]float64{0.5, 1},
+		},
+		{
+			// X's top row and left column share X1
+			name:     "Two lines at once",
+			at:       []int{0, 4, 1, 5, 2, 7, 3, 8, 6},
+			expected: [2]float64{2, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGame()
+			for _, s := range tt.at {
+				g.marks = append(g.marks, mark{[2]int{s, s}, s})
+			}
+			if scores := g.Scores(); scores != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, scores)
+			}
+		})
+	}
+}
+
+func TestPlayMove(t *testing.T) {
+	g := NewGame()
+	if err := g.PlayMove("0,0 0,0"); err == nil {
+		t.Error("expected a mark in a single square to be rejected")
+	}
+	for _, input := range []string{"0,0 0,1", "0,1 0,2", "0,0 0,2"} {
+		if err := g.PlayMove(input); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// O collapses X3 first, and then places O4 as the second half of the move
+	if err := g.PlayMove("1,1"); err == nil {
+		t.Error("expected a collapse outside X3's squares to be rejected")
+	}
+	if err := g.PlayMove("0,2"); err != nil {
+		t.Fatal(err)
+	}
+	if g.PlayerToMove() != 1 {
+		t.Fatal("expected O to place a mark after the collapse")
+	}
+	if err := g.PlayMove("0,0 1,1"); err == nil {
+		t.Error("expected a mark on a classical square to be rejected")
+	}
+	if err := g.PlayMove("1,1 2,2"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Move{Collapse: 2, A: 4, B: 8}
+	if last := g.history[len(g.history)-1]; last != expected || g.PlayerToMove() != 0 {
+		t.Errorf("expected %v with X to move, got %v", expected, last)
+	}
+}
+
+func TestAIGameEnds(t *testing.T) {
+	g := NewGame()
+	for moves := 0; moves < 10; moves++ {
+		if over, _ := g.Result(); over {
+			return
+		}
+		if err := g.PlayAIMove(context.Background(), 3, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Errorf("expected the game to be over after 10 moves, got %v", g.history)
+}
@@ -0,0 +1,265 @@
+// Package qubic implements Qubic, tic-tac-toe in a 4x4x4 cube. A player wins
+// with four in a row along any of the cube's 76 lines, which run through a
+// layer, straight down through the layers or diagonally across them,
+// including the four space diagonals from corner to corner.
+package qubic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+)
+
+// Size is the number of layers, rows and columns of the cube.
+const Size = 4
+
+const emptySpace = " "
+
+// Move is a space in the cube.
+type Move struct {
+	Layer int
+	Row   int
+	Col   int
+}
+
+// lines are the 76 winning lines, and linesThrough the lines through each space.
+var (
+	lines        [][Size]Move
+	linesThrough [Size][Size][Size][]int
+)
+
+func init() {
+	// Every direction and its opposite give the same lines, so only the
+	// directions whose first non-zero step is positive are used
+	for dl := -1; dl <= 1; dl++ {
+		for dr := -1; dr <= 1; dr++ {
+			for dc := -1; dc <= 1; dc++ {
+				if dl < 0 || (dl == 0 && dr < 0) || (dl == 0 && dr == 0 && dc <= 0) {
+					continue
+				}
+				addLines(dl, dr, dc)
+			}
+		}
+	}
+}
+
+// addLines adds every line that runs all the way through the cube in the
+// direction.
+func addLines(dl int, dr int, dc int) {
+	for l := 0; l < Size; l++ {
+		for r := 0; r < Size; r++ {
+			for c := 0; c < Size; c++ {
+				last := Move{l + dl*(Size-1), r + dr*(Size-1), c + dc*(Size-1)}
+				if !inCube(last) || inCube(Move{l - dl, r - dr, c - dc}) {
+					continue
+				}
+
+				var line [Size]Move
+				for i := range line {
+					line[i] = Move{l + dl*i, r + dr*i, c + dc*i}
+					linesThrough[line[i].Layer][line[i].Row][line[i].Col] = append(linesThrough[line[i].Layer][line[i].Row][line[i].Col], len(lines))
+				}
+				lines = append(lines, line)
+			}
+		}
+	}
+}
+
+func inCube(m Move) bool {
+	return m.Layer >= 0 && m.Layer < Size && m.Row >= 0 && m.Row < Size && m.Col >= 0 && m.Col < Size
+}
+
+type Game struct {
+	spaces  [Size][Size][Size]string
+	toMove  int // index of the player to move, 0 for X
+	history []Move
+	winner  int // index of the winner, -1 while nobody has won
+}
+
+func NewGame() *Game {
+	g := &Game{winner: -1}
+	for l := 0; l < Size; l++ {
+		for r := 0; r < Size; r++ {
+			for c := 0; c < Size; c++ {
+				g.spaces[l][r][c] = emptySpace
+			}
+		}
+	}
+	return g
+}
+
+// GetToken returns the token at the given space.
+func (g *Game) GetToken(move Move) string {
+	return g.spaces[move.Layer][move.Row][move.Col]
+}
+
+// PlayerToMove returns the index of the player to move, 0 for X and 1 for O.
+func (g *Game) PlayerToMove() int {
+	return g.toMove
+}
+
+// Moves returns the open spaces, the ones on the most lines first. Searching
+// strong moves first lets the alpha-beta search prune more of the tree.
+func (g *Game) Moves() []Move {
+	if over, _ := g.Result(); over {
+		return nil
+	}
+
+	var strong, weak []Move
+	for l := 0; l < Size; l++ {
+		for r := 0; r < Size; r++ {
+			for c := 0; c < Size; c++ {
+				if g.spaces[l][r][c] != emptySpace {
+					continue
+				}
+				if len(linesThrough[l][r][c]) > 4 {
+					strong = append(strong, Move{l, r, c})
+				} else {
+					weak = append(weak, Move{l, r, c})
+				}
+			}
+		}
+	}
+	return append(strong, weak...)
+}
+
+// Play places the token of the player to move. The space must be open.
+func (g *Game) Play(move Move) {
+	token := minmax.Tokens[g.toMove]
+	g.spaces[move.Layer][move.Row][move.Col] = token
+	g.history = append(g.history, move)
+
+	// Only lines through the new token can have been completed
+	for _, i := range linesThrough[move.Layer][move.Row][move.Col] {
+		if g.lineCount(i, token) == Size {
+			g.winner = g.toMove
+			break
+		}
+	}
+	g.toMove = 1 - g.toMove
+}
+
+// Undo takes back the last move.
+func (g *Game) Undo(move Move) {
+	g.spaces[move.Layer][move.Row][move.Col] = emptySpace
+	g.history = g.history[:len(g.history)-1]
+	g.winner = -1
+	g.toMove = 1 - g.toMove
+}
+
+// lineCount counts the tokens on the line.
+func (g *Game) lineCount(line int, token string) int {
+	count := 0
+	for _, space := range lines[line] {
+		if g.GetToken(space) == token {
+			count++
+		}
+	}
+	return count
+}
+
+// Outcome reports whether the game is over and, if so, the result for the
+// player to move.
+func (g *Game) Outcome() (bool, minmax.Outcome) {
+	over, winner := g.Result()
+	return minmax.OutcomeFor(over, winner, g.toMove)
+}
+
+// Result reports whether the game is over and the index of the winner, or
+// -1 for a draw.
+func (g *Game) Result() (bool, int) {
+	if g.winner != -1 {
+		return true, g.winner
+	}
+	return len(g.history) == Size*Size*Size, -1
+}
+
+// lineWeights scores an open line by the number of tokens a player has on it.
+// A line with three is a threat the opponent has to answer.
+var lineWeights = [Size + 1]int{0, 1, 8, 64, 0}
+
+// Evaluate scores the position for the player to move by the lines that only
+// one of the players has tokens on. Having the move is worth a lot when both
+// players have threats, so the player to move gets their lines counted double.
+func (g *Game) Evaluate() int {
+	me, opponent := minmax.Tokens[g.toMove], minmax.Tokens[1-g.toMove]
+
+	score := 0
+	for i := range lines {
+		mine, theirs := g.lineCount(i, me), g.lineCount(i, opponent)
+		if theirs == 0 {
+			score += 2 * lineWeights[mine]
+		}
+		if mine == 0 {
+			score -= lineWeights[theirs]
+		}
+	}
+	return score
+}
+
+// PlayAIMove plays the move minmax.PlayWithin finds for the AI difficulty,
+// which gains a ply every three levels. A time budget searches up to every
+// cell of the cube.
+func (g *Game) PlayAIMove(ctx context.Context, difficulty int, budget time.Duration) error {
+	return minmax.PlayWithin(ctx, g, minmax.AIDepth(difficulty, 1, 3), budget, Size*Size*Size)
+}
+
+// MoveFormat describes the move input.
+func (g *Game) MoveFormat() string {
+	return "layer,row,col"
+}
+
+// ParseMove parses a move string in the format "layer,row,col". Returns an
+// error if the move is not in the correct format or is outside the cube.
+func ParseMove(input string) (Move, error) {
+	var move Move
+	_, err := fmt.Sscanf(strings.TrimSpace(input), "%d,%d,%d", &move.Layer, &move.Row, &move.Col)
+	if err != nil {
+		return Move{}, err
+	}
+
+	if !inCube(move) {
+		return Move{}, fmt.Errorf("invalid move")
+	}
+	return move, nil
+}
+
+// PlayMove parses a move and plays it for the player to move.
+func (g *Game) PlayMove(input string) error {
+	move, err := ParseMove(input)
+	if err != nil {
+		return err
+	}
+	if over, _ := g.Result(); over || g.GetToken(move) != emptySpace {
+		return fmt.Errorf("That space is already occupied. Please try again.")
+	}
+	g.Play(move)
+	return nil
+}
+
+// PrintBoard prints the layers of the cube side by side, top layer first.
+func (g *Game) PrintBoard() {
+	var header, columns, line []string
+	for l := 0; l < Size; l++ {
+		header = append(header, fmt.Sprintf("layer %d", l))
+		columns = append(columns, "0|1|2|3")
+		line = append(line, "-------")
+	}
+
+	fmt.Println("")
+	fmt.Printf("   %s\n", strings.Join(header, "     "))
+	fmt.Printf("   %s\n", strings.Join(columns, "     "))
+	for r := 0; r < Size; r++ {
+		fmt.Printf("   %s\n", strings.Join(line, "     "))
+
+		var rows []string
+		for l := 0; l < Size; l++ {
+			rows = append(rows, fmt.Sprintf("%d %s", r, strings.Join(g.spaces[l][r][:], "|")))
+		}
+		fmt.Printf(" %s \n", strings.Join(rows, "   "))
+	}
+	fmt.Println("")
+}
@@ -0,0 +1,89 @@
+package qubic
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	if len(lines) != 76 {
+		t.Fatalf("expected 76 lines, got %d", len(lines))
+	}
+
+	// The corners and the centre spaces are on 7 lines, the others on 4
+	tests := []struct {
+		name  string
+		space Move
+		lines int
+	}{
+		{"Corner", Move{0, 0, 0}, 7},
+		{"Centre", Move{1, 1, 1}, 7},
+		{"Edge", Move{0, 0, 1}, 4},
+		{"Face centre", Move{0, 1, 1}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(linesThrough[tt.space.Layer][tt.space.Row][tt.space.Col]); got != tt.lines {
+				t.Errorf("expected %d lines, got %d", tt.lines, got)
+			}
+		})
+	}
+}
+
+func TestSpaceDiagonalWins(t *testing.T) {
+	g := NewGame()
+	for i := 0; i < Size; i++ {
+		g.Play(Move{i, i, Size - 1 - i}) // X
+		if over, _ := g.Result(); over != (i == Size-1) {
+			t.Fatalf("move %d: expected game over to be %v", i, i == Size-1)
+		}
+		if i < Size-1 {
+			g.Play(Move{i, Size - 1, 0}) // O
+		}
+	}
+
+	if over, winner := g.Result(); !over || winner != 0 {
+		t.Fatalf("expected X to win, got %v %d", over, winner)
+	}
+
+	g.Undo(Move{Size - 1, Size - 1, 0})
+	if over, _ := g.Result(); over {
+		t.Error("undo should take back the win")
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Move
+		wantErr bool
+	}{
+		{"1,2,3\n", Move{1, 2, 3}, false},
+		{"0,0,0", Move{0, 0, 0}, false},
+		{"1,2\n", Move{}, true},
+		{"4,0,0\n", Move{}, true},
+		{"a,b,c\n", Move{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMove(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMove(%q) = %v, %v", tt.input, got, err)
+		}
+	}
+}
+
+func TestAIBlocksThreat(t *testing.T) {
+	g := NewGame()
+	// X has three on the top row of layer 0, O must block the fourth
+	moves := []Move{{0, 0, 0}, {3, 3, 3}, {0, 0, 1}, {3, 3, 2}, {0, 0, 2}}
+	for _, move := range moves {
+		g.Play(move)
+	}
+
+	if err := g.PlayAIMove(context.Background(), 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	if g.GetToken(Move{0, 0, 3}) != "O" {
+		t.Error("expected O to block at 0,0,3")
+	}
+}
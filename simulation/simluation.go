@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
 	"time"
 
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/book"
 	"github.com/jackmcdermo/tic-tac-toe-/game"
+	"github.com/jackmcdermo/tic-tac-toe-/learn"
 	"github.com/jackmcdermo/tic-tac-toe-/minmax"
 )
 
@@ -20,6 +28,14 @@ type Results struct {
 	TotalDuration      float64
 	Player1Duration    float64
 	Player2Duration    float64
+	Player1Nodes       int
+	Player2Nodes       int
+	Player1Leaves      int
+	Player2Leaves      int
+	Player1MaxDepth    int
+	Player2MaxDepth    int
+	Player1Blunders    int
+	Player2Blunders    int
 }
 
 type Simulation struct {
@@ -27,6 +43,17 @@ type Simulation struct {
 	Player2Difficulty int
 	TotalRounds       int
 	RandomAI          bool
+
+	// Book, when set, picks the moves of both players for the plies it
+	// covers, and learns from the result of every game
+	Book *book.Book
+
+	// Agent, when set, plays player 1 in place of the search, and player 2
+	// as well with SelfPlay. While Training it explores and learns from the
+	// result of every game.
+	Agent    *learn.Agent
+	Training bool
+	SelfPlay bool
 }
 
 func NewSimulation(player1Difficulty int, player2Difficulty int, totalRounds int, randomAI bool) Simulation {
@@ -50,6 +77,14 @@ func (s *Simulation) RunSimulation() Results {
 		TotalDuration:      0,
 		Player1Duration:    0,
 		Player2Duration:    0,
+		Player1Nodes:       0,
+		Player2Nodes:       0,
+		Player1Leaves:      0,
+		Player2Leaves:      0,
+		Player1MaxDepth:    0,
+		Player2MaxDepth:    0,
+		Player1Blunders:    0,
+		Player2Blunders:    0,
 	}
 
 	start := time.Now()
@@ -78,24 +113,79 @@ func (s *Simulation) simulateGame(results *Results) {
 	for !gameInstance.Board.CheckWin() && !gameInstance.Board.CheckTie() {
 		moveStart := time.Now()
 		player := gameInstance.NextMovePlayer()
-		row, col := minmax.GetBestMove(*gameInstance.Board, player.AiPlayerDifficulty, player.Token)
+		move, stats := s.move(gameInstance, player)
 		moveDuration := time.Since(moveStart).Seconds()
 		if player.Token == "X" {
 			results.Player1Duration += moveDuration
+			results.Player1Nodes += stats.Nodes
+			results.Player1Leaves += stats.LeafEvaluations
+			results.Player1MaxDepth = max(results.Player1MaxDepth, stats.MaxDepth)
 		} else {
 			results.Player2Duration += moveDuration
+			results.Player2Nodes += stats.Nodes
+			results.Player2Leaves += stats.LeafEvaluations
+			results.Player2MaxDepth = max(results.Player2MaxDepth, stats.MaxDepth)
+		}
+		gameInstance.DoMove(move.Row, move.Col)
+	}
+
+	// Count the moves that made the result worse for the player. Solving
+	// every game costs more than a training game, so it is left out then.
+	if !s.Training {
+		for _, review := range minmax.ReviewGame(gameInstance.History(), minmax.Options{}) {
+			if !review.Blunder() {
+				continue
+			}
+			if review.Move.Token == "X" {
+				results.Player1Blunders++
+			} else {
+				results.Player2Blunders++
+			}
 		}
-		gameInstance.DoMove(row, col)
 	}
 
 	// Update the results based on the outcome of the game
+	winner := ""
 	if gameInstance.Board.CheckWinForPlayer("X") {
 		results.Player1Wins++
+		winner = "X"
 	} else if gameInstance.Board.CheckWinForPlayer("O") {
 		results.Player2Wins++
+		winner = "O"
 	} else {
 		results.Ties++
 	}
+	if s.Book != nil {
+		s.Book.Record(gameInstance.History(), winner)
+	}
+	if s.Agent != nil && s.Training {
+		s.Agent.Learn(gameInstance.History(), winner)
+	}
+}
+
+// move picks the player's move: from the book if it covers the position,
+// from the agent if it plays the player, and from a search of the player's
+// AI level otherwise. Only a search has stats.
+func (s *Simulation) move(gameInstance *game.Game, player game.Player) (minmax.Move, minmax.Stats) {
+	if s.Book != nil {
+		if row, col, ok := s.Book.Choose(*gameInstance.Board, player.Token); ok {
+			return minmax.Move{Row: row, Col: col}, minmax.Stats{}
+		}
+	}
+
+	if s.Agent != nil && (player.Token == "X" || s.SelfPlay) {
+		agentMove := s.Agent.BestMove
+		if s.Training {
+			agentMove = s.Agent.TrainingMove
+		}
+		if row, col, ok := agentMove(*gameInstance.Board, player.Token); ok {
+			return minmax.Move{Row: row, Col: col}, minmax.Stats{}
+		}
+	}
+
+	profile := minmax.ProfileFor(player.AiPlayerDifficulty)
+	move, stats, _ := minmax.GetPlacementWithProfile(context.Background(), *gameInstance.Board, profile, player.Token, minmax.Options{Evaluator: minmax.DefaultEvaluator})
+	return move, stats
 }
 
 func main() {
@@ -105,10 +195,38 @@ func main() {
 	rounds := flag.Int("r", 100, "The number of rounds to simulate")
 	randomAI := flag.String("rai", "no", "Use random AI for player 2 (use 'yes' or 'no')")
 	matrix := flag.String("matrix", "no", "Run full matrix of simulations")
+	calibrate := flag.String("calibrate", "no", "Play every AI level against perfect play, as each player (use 'yes' or 'no')")
+	bookFile := flag.String("book", "", "The opening book file to play the first moves from and learn into, none if empty")
+	bookPlies := flag.Int("bookplies", 2, "The number of plies a new opening book covers")
+	agentFile := flag.String("agent", "", "The learned agent file to train, or to play player 1 with, none if empty")
+	episodes := flag.Int("train", 0, "The number of games to train the agent for, against player 2 or itself")
+	every := flag.Int("every", 1000, "The number of training games between measurements of the agent against player 2")
+	learningRate := flag.Float64("alpha", 0.2, "The learning rate of the agent")
+	exploration := flag.Float64("epsilon", 0.1, "The chance of a random move by the agent while training")
+	selfPlay := flag.String("selfplay", "no", "Train the agent against itself instead of player 2 (use 'yes' or 'no')")
 	flag.Parse()
 
-	if *matrix == "yes" {
-		runTestMatrix(*rounds)
+	openingBook, err := loadBook(*bookFile, *bookPlies)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	agent, err := loadAgent(*agentFile, *learningRate, *exploration)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if *episodes > 0 {
+		if agent == nil {
+			fmt.Println("Training needs an agent file to save the agent to, see -agent.")
+			return
+		}
+		runTraining(agent, *episodes, *every, *player2, *rounds, *selfPlay == "yes")
+	} else if *matrix == "yes" {
+		runTestMatrix(*rounds, openingBook)
+	} else if *calibrate == "yes" {
+		runCalibration(*rounds)
 	} else {
 
 		randomAIBool := false
@@ -117,6 +235,8 @@ func main() {
 		}
 
 		simulation := NewSimulation(*player1, *player2, *rounds, randomAIBool)
+		simulation.Book = openingBook
+		simulation.Agent = agent
 
 		results := simulation.RunSimulation()
 
@@ -125,14 +245,98 @@ func main() {
 		fmt.Printf("Player 2 wins: %d\n", results.Player2Wins)
 		fmt.Printf("Ties: %d\n", results.Ties)
 		fmt.Printf("Duration (seconds): %f\n", results.TotalDuration)
+		fmt.Printf("Nodes searched: %d (player 1), %d (player 2)\n", results.Player1Nodes, results.Player2Nodes)
+		fmt.Printf("Blunders: %d (player 1), %d (player 2)\n", results.Player1Blunders, results.Player2Blunders)
 		fmt.Println("CSV Results")
 
 		writeHeaders()
 		resultsAsCSV(results, randomAIBool)
 	}
+
+	if openingBook != nil {
+		if err := writeFile(*bookFile, openingBook); err != nil {
+			fmt.Println(err)
+		}
+	}
+	if agent != nil && *episodes > 0 {
+		if err := writeFile(*agentFile, agent); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// loadBook reads the opening book in the file, or returns a new one covering
+// the plies if the file doesn't exist yet. Without a file there is no book.
+func loadBook(file string, plies int) (*book.Book, error) {
+	if file == "" {
+		return nil, nil
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return book.New(board.ClassicRules, plies), nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return book.Read(f)
 }
 
-func runTestMatrix(rounds int) {
+// loadAgent reads the learned agent in the file, or returns a new one for the
+// classic board if the file doesn't exist yet. Without a file there is no
+// agent.
+func loadAgent(file string, learningRate float64, exploration float64) (*learn.Agent, error) {
+	if file == "" {
+		return nil, nil
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return learn.NewAgent(board.ClassicRules, learningRate, exploration), nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return learn.Read(f, learningRate, exploration)
+}
+
+// writeFile writes a book or an agent back to its file.
+func writeFile(file string, w io.WriterTo) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := w.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runTraining trains the agent for the episodes, against itself or the AI
+// level of player 2. After every so many episodes it plays rounds against
+// player 2 without exploring or learning, and prints how the agent did, so
+// its progress can be charted against the episodes trained.
+func runTraining(agent *learn.Agent, episodes int, every int, opponent int, rounds int, selfPlay bool) {
+	fmt.Println("Episodes,Player 2 Difficulty,Wins,Ties,Losses,Win Rate,Not Lost,Positions")
+	for trained := 0; trained < episodes; {
+		block := min(every, episodes-trained)
+		training := NewSimulation(0, opponent, block, false)
+		training.Agent, training.Training, training.SelfPlay = agent, true, selfPlay
+		training.RunSimulation()
+		trained += block
+
+		measure := NewSimulation(0, opponent, rounds, false)
+		measure.Agent = agent
+		results := measure.RunSimulation()
+		winRate := float64(results.Player1Wins) / float64(results.TotalRounds)
+		notLost := float64(results.Player1Wins+results.Ties) / float64(results.TotalRounds)
+		fmt.Printf("%d,%d,%d,%d,%d,%.2f,%.2f,%d\n", trained, opponent+1, results.Player1Wins, results.Ties, results.Player2Wins, winRate, notLost, agent.Len())
+	}
+}
+
+// runTestMatrix plays every pair of AI levels. With an opening book the
+// games start from the book's moves, so the rounds of a pair don't all
+// replay the same game.
+func runTestMatrix(rounds int, openingBook *book.Book) {
 	writeHeaders()
 	// for i := 1; i <= 9; i++ {
 	// 	for j := 1; j <= i; j++ {
@@ -148,6 +352,7 @@ func runTestMatrix(rounds int) {
 	for i := 0; i <= 9; i++ {
 		for j := 0; j <= 9; j++ {
 			simulation := NewSimulation(i, j, rounds, false)
+			simulation.Book = openingBook
 			results := simulation.RunSimulation()
 			resultsAsCSV(results, false)
 		}
@@ -155,12 +360,38 @@ func runTestMatrix(rounds int) {
 
 	fmt.Println("Matrix simulation complete")
 }
+
+// runCalibration plays every AI level against the hardest level, which
+// plays perfectly, for the rounds as player 1 and for as many as player 2,
+// since player 1 always moves first. It prints the share of games each level
+// didn't lose, as each player and overall. The minmax.Profiles are tuned so
+// the overall share goes up evenly.
+func runCalibration(rounds int) {
+	perfect := len(minmax.Profiles) - 1
+	fmt.Println("Level,Losses,Ties,Wins,Not Lost as X,Not Lost as O,Not Lost")
+	for difficulty := 0; difficulty <= perfect; difficulty++ {
+		first := NewSimulation(difficulty, perfect, rounds, false)
+		asX := first.RunSimulation()
+		second := NewSimulation(perfect, difficulty, rounds, false)
+		asO := second.RunSimulation()
+
+		losses := asX.Player2Wins + asO.Player1Wins
+		wins := asX.Player1Wins + asO.Player2Wins
+		ties := asX.Ties + asO.Ties
+		notLostAsX := float64(asX.Player1Wins+asX.Ties) / float64(rounds)
+		notLostAsO := float64(asO.Player2Wins+asO.Ties) / float64(rounds)
+		notLost := float64(wins+ties) / float64(2*rounds)
+		fmt.Printf("%d,%d,%d,%d,%.2f,%.2f,%.2f\n", difficulty+1, losses, ties, wins, notLostAsX, notLostAsO, notLost)
+	}
+}
+
 func writeHeaders() {
 	// Print out the headers
-	fmt.Println("Total Rounds,Player 1 Wins,Player 1 Difficulty,Player 2 Wins,Player 2 Difficulty,Ties,Player 1 Starts First,Player1Duration,Player2Duration,TotalDuration,Random AI")
+	fmt.Println("Total Rounds,Player 1 Wins,Player 1 Difficulty,Player 2 Wins,Player 2 Difficulty,Ties,Player 1 Starts First,Player1Duration,Player2Duration,TotalDuration,Random AI,Player1Nodes,Player2Nodes,Player1Leaves,Player2Leaves,Player1MaxDepth,Player2MaxDepth,Player1Blunders,Player2Blunders")
 }
 
 func resultsAsCSV(results Results, randomAI bool) {
 	// Print out the results
-	fmt.Printf("%d,%d,%d,%d,%d,%d,%d,%f,%f,%f,%t\n", results.TotalRounds, results.Player1Wins, results.Player1Difficulty, results.Player2Wins, results.Player2Difficulty, results.Ties, results.Player1StartsFirst, results.Player1Duration, results.Player2Duration, results.TotalDuration, randomAI)
+	fmt.Printf("%d,%d,%d,%d,%d,%d,%d,%f,%f,%f,%t,%d,%d,%d,%d,%d,%d,%d,%d\n", results.TotalRounds, results.Player1Wins, results.Player1Difficulty, results.Player2Wins, results.Player2Difficulty, results.Ties, results.Player1StartsFirst, results.Player1Duration, results.Player2Duration, results.TotalDuration, randomAI,
+		results.Player1Nodes, results.Player2Nodes, results.Player1Leaves, results.Player2Leaves, results.Player1MaxDepth, results.Player2MaxDepth, results.Player1Blunders, results.Player2Blunders)
 }
@@ -0,0 +1,29 @@
+// Command gen generates the tablebase file embedded by package tablebase.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jackmcdermo/tic-tac-toe-/tablebase"
+)
+
+func main() {
+	output := flag.String("o", "tictactoe.tb", "The file to write the tablebase to")
+	flag.Parse()
+
+	table := tablebase.Generate()
+
+	file, err := os.Create(*output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := table.WriteTo(file); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Wrote %d positions to %s\n", table.Len(), *output)
+}
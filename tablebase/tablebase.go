@@ -0,0 +1,267 @@
+// Package tablebase holds the perfect-play result of every 3x3 position that
+// can be reached from an empty board, so the AI can look moves up instead of
+// searching for them. Positions are stored in their canonical orientation, so
+// symmetric positions share an entry.
+package tablebase
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+)
+
+//go:generate go run ./gen -o tictactoe.tb
+
+//go:embed tictactoe.tb
+var defaultTableFile []byte
+
+// magic starts every tablebase file, followed by the format version.
+const (
+	magic   = "TTTB"
+	version = 2
+)
+
+// An entry is packed into 16 bits: the best moves as a bit per space
+// (row*3 + col), the outcome offset by one and the plies to the end.
+const (
+	movesMask   = 1<<9 - 1
+	outcomeBit  = 9
+	pliesBit    = 11
+	oToMoveFlag = 1 << 15
+)
+
+// Entry is the perfect-play result of a position for the player to move.
+type Entry struct {
+	Outcome   minmax.Outcome
+	Plies     int           // plies until the game is won or lost, 0 for a draw
+	BestMoves []minmax.Move // every move that keeps the outcome, none if the game is over
+}
+
+// Table maps positions to their entries.
+type Table struct {
+	entries map[uint16]uint16
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultTable *Table
+)
+
+// Default returns the table generated into tictactoe.tb. It is read on the
+// first call and shared afterwards.
+func Default() *Table {
+	defaultOnce.Do(func() {
+		t, err := Read(bytes.NewReader(defaultTableFile))
+		if err != nil {
+			panic(fmt.Sprintf("tablebase: embedded table is corrupt: %v", err))
+		}
+		defaultTable = t
+	})
+	return defaultTable
+}
+
+// Len returns the number of canonical positions in the table.
+func (t *Table) Len() int {
+	return len(t.entries)
+}
+
+// Lookup returns the entry of the board with playerToken to move. It returns
+// false if the position can't be reached in a game, or the board isn't played
+// with the classic rules or has blocked spaces.
+func (t *Table) Lookup(b board.Board, playerToken string) (Entry, bool) {
+	if b.Rules() != board.ClassicRules || b.HasBlocked() {
+		return Entry{}, false
+	}
+
+	canonical, symmetry := b.Canonical()
+	value, ok := t.entries[encodeKey(*canonical, playerToken)]
+	if !ok {
+		return Entry{}, false
+	}
+
+	entry := Entry{
+		Outcome: minmax.Outcome(int(value>>outcomeBit&3) - 1),
+		Plies:   int(value >> pliesBit & 15),
+	}
+
+	// The moves are stored for the canonical board, turn them back
+	inverse := symmetry.Inverse()
+	moves := value & movesMask
+	for space := 0; space < 9; space++ {
+		if moves&(1<<space) != 0 {
+			row, col := inverse.MapMove(space/3, space%3)
+			entry.BestMoves = append(entry.BestMoves, minmax.Move{Row: row, Col: col})
+		}
+	}
+	return entry, true
+}
+
+// BestMove returns the move to play for playerToken. Of the moves that keep
+// the outcome, it picks the quickest win or the longest loss. It returns
+// false if the position isn't in the table or the game is over.
+func (t *Table) BestMove(b board.Board, playerToken string) (int, int, bool) {
+	entry, ok := t.Lookup(b, playerToken)
+	if !ok || len(entry.BestMoves) == 0 {
+		return -1, -1, false
+	}
+
+	opponentToken := otherToken(playerToken)
+	best := entry.BestMoves[0]
+	bestPlies := -1
+	for _, move := range entry.BestMoves {
+		b.PlaceToken(move.Row, move.Col, playerToken)
+		reply, _ := t.Lookup(b, opponentToken)
+		b.RemoveToken(move.Row, move.Col)
+
+		plies := reply.Plies + 1
+		if bestPlies == -1 || (entry.Outcome == minmax.Win && plies < bestPlies) || (entry.Outcome == minmax.Loss && plies > bestPlies) {
+			best, bestPlies = move, plies
+		}
+	}
+	return best.Row, best.Col, true
+}
+
+// Generate solves every position reachable from an empty board, with either
+// player moving first.
+func Generate() *Table {
+	t := &Table{entries: make(map[uint16]uint16)}
+	b := board.NewBoard()
+	b.InitBoard()
+	t.generate(*b, "X")
+	t.generate(*b, "O")
+	return t
+}
+
+// generate solves the board with playerToken to move and adds it and every
+// position reachable from it to the table.
+func (t *Table) generate(b board.Board, playerToken string) (minmax.Outcome, int) {
+	// Only the canonical orientation is stored, so solve that one
+	canonical, _ := b.Canonical()
+	b = *canonical
+	key := encodeKey(b, playerToken)
+	if value, ok := t.entries[key]; ok {
+		return minmax.Outcome(int(value>>outcomeBit&3) - 1), int(value >> pliesBit & 15)
+	}
+
+	opponentToken := otherToken(playerToken)
+	outcome, plies, moves := minmax.Loss, 0, uint16(0)
+	if b.CheckWinForPlayer(playerToken) {
+		outcome = minmax.Win
+	} else if b.CheckWinForPlayer(opponentToken) {
+		outcome = minmax.Loss
+	} else if b.CheckTie() {
+		outcome = minmax.Draw
+	} else {
+		plies = -1
+		for space := 0; space < 9; space++ {
+			row, col := space/3, space%3
+			if b.GetToken(row, col) != " " {
+				continue
+			}
+
+			b.PlaceToken(row, col, playerToken)
+			replyOutcome, replyPlies := t.generate(b, opponentToken)
+			b.RemoveToken(row, col)
+
+			moveOutcome, movePlies := -replyOutcome, replyPlies+1
+			switch {
+			case moveOutcome > outcome || plies == -1:
+				outcome, plies, moves = moveOutcome, movePlies, 1<<space
+			case moveOutcome == outcome:
+				moves |= 1 << space
+				if (outcome == minmax.Win && movePlies < plies) || (outcome == minmax.Loss && movePlies > plies) {
+					plies = movePlies
+				}
+			}
+		}
+		if outcome == minmax.Draw {
+			plies = 0
+		}
+	}
+
+	t.entries[key] = moves | uint16(outcome+1)<<outcomeBit | uint16(plies)<<pliesBit
+	return outcome, plies
+}
+
+// WriteTo writes the table in its binary format: the magic, the version, the
+// number of entries and then each key and entry, sorted by key.
+func (t *Table) WriteTo(w io.Writer) (int64, error) {
+	keys := make([]uint16, 0, len(t.entries))
+	for key := range t.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	buf := bytes.NewBufferString(magic)
+	buf.WriteByte(version)
+	binary.Write(buf, binary.LittleEndian, uint32(len(keys)))
+	for _, key := range keys {
+		binary.Write(buf, binary.LittleEndian, [2]uint16{key, t.entries[key]})
+	}
+	return buf.WriteTo(w)
+}
+
+// Read reads a table written by WriteTo.
+func Read(r io.Reader) (*Table, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, errors.New("not a tablebase file")
+	}
+	if header[len(magic)] != version {
+		return nil, fmt.Errorf("unsupported tablebase version %d", header[len(magic)])
+	}
+
+	var count uint32
+	if err := binary.Read(br, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+
+	t := &Table{entries: make(map[uint16]uint16, count)}
+	for i := uint32(0); i < count; i++ {
+		var entry [2]uint16
+		if err := binary.Read(br, binary.LittleEndian, &entry); err != nil {
+			return nil, err
+		}
+		t.entries[entry[0]] = entry[1]
+	}
+	return t, nil
+}
+
+// encodeKey packs the board into a base 3 number, one digit per space, with
+// the top bit set when O is to move.
+func encodeKey(b board.Board, playerToken string) uint16 {
+	var key uint16
+	for space := 8; space >= 0; space-- {
+		key *= 3
+		switch b.GetToken(space/3, space%3) {
+		case "X":
+			key += 1
+		case "O":
+			key += 2
+		}
+	}
+	if playerToken == "O" {
+		key |= oToMoveFlag
+	}
+	return key
+}
+
+func otherToken(playerToken string) string {
+	if playerToken == "X" {
+		return "O"
+	}
+	return "X"
+}
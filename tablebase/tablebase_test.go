@@ -0,0 +1,136 @@
+package tablebase
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+)
+
+func TestDefaultMatchesGenerate(t *testing.T) {
+	generated := Generate()
+	embedded := Default()
+	if embedded.Len() != generated.Len() {
+		t.Fatalf("expected %d positions, got %d; run go generate", generated.Len(), embedded.Len())
+	}
+	for key, value := range generated.entries {
+		if embedded.entries[key] != value {
+			t.Fatalf("entry %d differs from the generated table; run go generate", key)
+		}
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	table := Generate()
+
+	var buf bytes.Buffer
+	if _, err := table.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 4+1+4+4*table.Len() {
+		t.Errorf("unexpected file size %d for %d positions", buf.Len(), table.Len())
+	}
+
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Len() != table.Len() {
+		t.Fatalf("expected %d positions, got %d", table.Len(), read.Len())
+	}
+	for key, value := range table.entries {
+		if read.entries[key] != value {
+			t.Fatalf("entry %d was not read back", key)
+		}
+	}
+
+	if _, err := Read(bytes.NewBufferString("not a table")); err == nil {
+		t.Error("expected an error reading an invalid file")
+	}
+}
+
+func TestLookupMatchesGetBestMove(t *testing.T) {
+	table := Default()
+
+	for key := range table.entries {
+		canonical, playerToken := decodeKey(key)
+
+		// Every orientation of the position shares the canonical entry
+		for _, symmetry := range board.Symmetries {
+			b := *canonical.Transform(symmetry)
+			entry, ok := table.Lookup(b, playerToken)
+			if !ok {
+				t.Fatalf("position %s not found", b.Key())
+			}
+			if b.CheckWin() || b.CheckTie() {
+				if len(entry.BestMoves) != 0 {
+					t.Errorf("position %s is over but has best moves", b.Key())
+				}
+				continue
+			}
+
+			row, col, stats, err := minmax.GetBestMoveStats(context.Background(), b, 9, playerToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bestScore := stats.RootScores[0].Score
+			for _, rootScore := range stats.RootScores {
+				bestScore = max(bestScore, rootScore.Score)
+			}
+			if minmax.Outcome(bestScore) != entry.Outcome {
+				t.Errorf("position %s: GetBestMove scores %d, table has %s", b.Key(), bestScore, entry.Outcome)
+			}
+
+			found := false
+			for _, move := range entry.BestMoves {
+				found = found || move == (minmax.Move{Row: row, Col: col})
+			}
+			if !found {
+				t.Errorf("position %s: GetBestMove plays %d,%d, not one of %v", b.Key(), row, col, entry.BestMoves)
+			}
+
+			row, col, ok = table.BestMove(b, playerToken)
+			if !ok || b.GetToken(row, col) != " " {
+				t.Errorf("position %s: BestMove returned %d,%d", b.Key(), row, col)
+			}
+		}
+	}
+}
+
+func TestBestMoveWinsQuickly(t *testing.T) {
+	b := board.NewBoard()
+	b.SetStartingBoard([3][3]string{
+		{"X", " ", " "},
+		{"O", "X", " "},
+		{"O", " ", " "},
+	})
+
+	row, col, ok := Default().BestMove(*b, "X")
+	if !ok || row != 2 || col != 2 {
+		t.Errorf("expected the winning move 2,2, got %d,%d", row, col)
+	}
+}
+
+func decodeKey(key uint16) (*board.Board, string) {
+	playerToken := "X"
+	if key&oToMoveFlag != 0 {
+		playerToken = "O"
+		key &^= oToMoveFlag
+	}
+
+	b := board.NewBoard()
+	b.InitBoard()
+	for space := 0; space < 9; space++ {
+		switch key % 3 {
+		case 1:
+			b.PlaceToken(space/3, space%3, "X")
+		case 2:
+			b.PlaceToken(space/3, space%3, "O")
+		}
+		key /= 3
+	}
+	return b, playerToken
+}
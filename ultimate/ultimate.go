@@ -0,0 +1,302 @@
+// Package ultimate implements Ultimate tic-tac-toe, played on a 3x3 grid of
+// small 3x3 boards. The space picked on a small board sends the opponent to
+// the small board in the same position for their next move. Winning a small
+// board claims its space on the meta-board, and three claimed spaces in a row
+// win the game.
+package ultimate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackmcdermo/tic-tac-toe-/board"
+	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+)
+
+// drawnBoard marks a small board on the meta-board that filled up without a
+// winner. It can't be part of a winning line for either player.
+const drawnBoard = "-"
+
+// Move is a space on one of the small boards.
+type Move struct {
+	BoardRow int
+	BoardCol int
+	Row      int
+	Col      int
+}
+
+// played is a move along with the state needed to undo it.
+type played struct {
+	move      Move
+	forcedRow int
+	forcedCol int
+	decided   bool // the move decided its small board
+}
+
+type Game struct {
+	boards    [3][3]*board.Board
+	meta      *board.Board
+	toMove    int // index of the player to move, 0 for X
+	forcedRow int // the small board the next move must be on, -1 if any
+	forcedCol int
+	history   []played
+}
+
+func NewGame() *Game {
+	g := &Game{
+		meta:      board.NewBoard(),
+		forcedRow: -1,
+		forcedCol: -1,
+	}
+	g.meta.InitBoard()
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			g.boards[i][j] = board.NewBoard()
+			g.boards[i][j].InitBoard()
+		}
+	}
+	return g
+}
+
+// PlayerToMove returns the index of the player to move, 0 for X and 1 for O.
+func (g *Game) PlayerToMove() int {
+	return g.toMove
+}
+
+// ForcedBoard returns the small board the next move must be played on, and
+// false if the player may pick any small board.
+func (g *Game) ForcedBoard() (int, int, bool) {
+	return g.forcedRow, g.forcedCol, g.forcedRow != -1
+}
+
+// Moves returns the legal moves of the player to move.
+func (g *Game) Moves() []Move {
+	if over, _ := g.Result(); over {
+		return nil
+	}
+
+	var moves []Move
+	for br := 0; br < 3; br++ {
+		for bc := 0; bc < 3; bc++ {
+			if g.forcedRow != -1 && (br != g.forcedRow || bc != g.forcedCol) {
+				continue
+			}
+			if g.meta.GetToken(br, bc) != " " {
+				continue
+			}
+			for r := 0; r < 3; r++ {
+				for c := 0; c < 3; c++ {
+					if g.boards[br][bc].GetToken(r, c) == " " {
+						moves = append(moves, Move{br, bc, r, c})
+					}
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// IsLegal reports whether the player to move may play the move.
+func (g *Game) IsLegal(move Move) bool {
+	for _, legal := range g.Moves() {
+		if legal == move {
+			return true
+		}
+	}
+	return false
+}
+
+// Play makes the move for the player to move. The move must be legal.
+func (g *Game) Play(move Move) {
+	token := minmax.Tokens[g.toMove]
+	small := g.boards[move.BoardRow][move.BoardCol]
+	small.PlaceToken(move.Row, move.Col, token)
+
+	// Only the mover can have completed a line on the small board
+	p := played{move: move, forcedRow: g.forcedRow, forcedCol: g.forcedCol}
+	if small.CheckWin() {
+		g.meta.PlaceToken(move.BoardRow, move.BoardCol, token)
+		p.decided = true
+	} else if small.CheckTie() {
+		g.meta.PlaceToken(move.BoardRow, move.BoardCol, drawnBoard)
+		p.decided = true
+	}
+	g.history = append(g.history, p)
+
+	// The opponent is sent to the board matching the space, unless it is
+	// already decided, in which case they may play anywhere
+	g.forcedRow, g.forcedCol = -1, -1
+	if g.meta.GetToken(move.Row, move.Col) == " " {
+		g.forcedRow, g.forcedCol = move.Row, move.Col
+	}
+	g.toMove = 1 - g.toMove
+}
+
+// Undo takes back the last move.
+func (g *Game) Undo(move Move) {
+	p := g.history[len(g.history)-1]
+	g.history = g.history[:len(g.history)-1]
+
+	g.boards[move.BoardRow][move.BoardCol].RemoveToken(move.Row, move.Col)
+	if p.decided {
+		g.meta.RemoveToken(move.BoardRow, move.BoardCol)
+	}
+	g.forcedRow, g.forcedCol = p.forcedRow, p.forcedCol
+	g.toMove = 1 - g.toMove
+}
+
+// Outcome reports whether the game is over and, if so, the result for the
+// player to move.
+func (g *Game) Outcome() (bool, minmax.Outcome) {
+	over, winner := g.Result()
+	return minmax.OutcomeFor(over, winner, g.toMove)
+}
+
+// Result reports whether the game is over and the index of the winner, or
+// -1 for a draw.
+func (g *Game) Result() (bool, int) {
+	for player, token := range minmax.Tokens {
+		if g.meta.CheckWinForPlayer(token) {
+			return true, player
+		}
+	}
+	// The next move is always on an undecided board, so there is one left
+	// until every small board is decided
+	if g.meta.CheckTie() {
+		return true, -1
+	}
+	return false, -1
+}
+
+// lineWeights scores an open line by the number of tokens a player has on it.
+var lineWeights = [4]int{0, 1, 4, 16}
+
+// boardWeights favours the small boards that are part of more lines of the
+// meta-board.
+var boardWeights = [3][3]int{
+	{3, 2, 3},
+	{2, 4, 2},
+	{3, 2, 3},
+}
+
+// Evaluate scores the position for the player to move by the lines that are
+// still open to each player, on the meta-board and on every small board that
+// is undecided.
+func (g *Game) Evaluate() int {
+	me, opponent := minmax.Tokens[g.toMove], minmax.Tokens[1-g.toMove]
+
+	score := 20 * lineScore(g.meta, me, opponent)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			switch g.meta.GetToken(i, j) {
+			case me:
+				score += 10 * boardWeights[i][j]
+			case opponent:
+				score -= 10 * boardWeights[i][j]
+			case " ":
+				score += boardWeights[i][j] * lineScore(g.boards[i][j], me, opponent)
+			}
+		}
+	}
+	return score
+}
+
+// lineScore weighs the lines of the board that only one of the players has
+// tokens on, positive for me and negative for the opponent.
+func lineScore(b *board.Board, me string, opponent string) int {
+	score := 0
+	b.EachLine(func(tokens []string) {
+		mine, theirs, blocked := 0, 0, 0
+		for _, token := range tokens {
+			switch token {
+			case me:
+				mine++
+			case opponent:
+				theirs++
+			case drawnBoard:
+				blocked++
+			}
+		}
+		if blocked > 0 {
+			return
+		}
+		if theirs == 0 {
+			score += lineWeights[mine]
+		}
+		if mine == 0 {
+			score -= lineWeights[theirs]
+		}
+	})
+	return score
+}
+
+// PlayAIMove plays the move minmax.PlayWithin finds for the AI difficulty,
+// which gains two plies every three levels. The tree is too wide to search as
+// deep as on a single board. A time budget searches up to the 81 spaces.
+func (g *Game) PlayAIMove(ctx context.Context, difficulty int, budget time.Duration) error {
+	return minmax.PlayWithin(ctx, g, minmax.AIDepth(difficulty, 2, 3), budget, 81)
+}
+
+// MoveFormat describes the move input expected from the player to move.
+func (g *Game) MoveFormat() string {
+	if row, col, forced := g.ForcedBoard(); forced {
+		return fmt.Sprintf("row,col on board %d,%d", row, col)
+	}
+	return "board row,board col,row,col"
+}
+
+// PlayMove parses a move in the MoveFormat and plays it for the player to move.
+func (g *Game) PlayMove(input string) error {
+	input = strings.TrimSpace(input)
+
+	var move Move
+	if _, err := fmt.Sscanf(input, "%d,%d,%d,%d", &move.BoardRow, &move.BoardCol, &move.Row, &move.Col); err != nil {
+		// On a forced board the board may be left out
+		row, col, forced := g.ForcedBoard()
+		if !forced {
+			return err
+		}
+		move.BoardRow, move.BoardCol = row, col
+		if _, err := fmt.Sscanf(input, "%d,%d", &move.Row, &move.Col); err != nil {
+			return err
+		}
+	}
+
+	if !g.IsLegal(move) {
+		return fmt.Errorf("invalid move")
+	}
+	g.Play(move)
+	return nil
+}
+
+// PrintBoard prints the small boards in their grid, followed by the meta-board
+// showing who won which small board.
+func (g *Game) PrintBoard() {
+	fmt.Println("")
+	fmt.Println("        0       1       2")
+	fmt.Println("      0|1|2   0|1|2   0|1|2")
+	for br := 0; br < 3; br++ {
+		if br > 0 {
+			fmt.Println("      ======#=======#======")
+		}
+		for r := 0; r < 3; r++ {
+			label := " "
+			if r == 0 {
+				label = fmt.Sprint(br)
+			}
+
+			var smallRows []string
+			for bc := 0; bc < 3; bc++ {
+				small := g.boards[br][bc]
+				smallRows = append(smallRows, fmt.Sprintf("%s|%s|%s", small.GetToken(r, 0), small.GetToken(r, 1), small.GetToken(r, 2)))
+			}
+			fmt.Printf(" %s  %d %s\n", label, r, strings.Join(smallRows, " # "))
+		}
+	}
+
+	fmt.Println("")
+	fmt.Println("Small boards won:")
+	g.meta.PrintBoard()
+}
@@ -0,0 +1,118 @@
+package ultimate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackmcdermo/tic-tac-toe-/minmax"
+)
+
+func TestMoveSendsOpponentToBoard(t *testing.T) {
+	g := NewGame()
+	if len(g.Moves()) != 81 {
+		t.Fatalf("expected 81 moves on an empty board, got %d", len(g.Moves()))
+	}
+
+	g.Play(Move{1, 1, 0, 2})
+	row, col, forced := g.ForcedBoard()
+	if !forced || row != 0 || col != 2 {
+		t.Fatalf("expected to be sent to board 0,2, got %d,%d (%v)", row, col, forced)
+	}
+	for _, move := range g.Moves() {
+		if move.BoardRow != 0 || move.BoardCol != 2 {
+			t.Errorf("move %v is not on the forced board", move)
+		}
+	}
+	if g.IsLegal(Move{1, 1, 0, 0}) {
+		t.Error("a move off the forced board should not be legal")
+	}
+}
+
+func TestWinningSmallBoard(t *testing.T) {
+	g := NewGame()
+	moves := []Move{
+		{1, 1, 0, 0}, // X
+		{0, 0, 1, 1}, // O
+		{1, 1, 0, 1}, // X
+		{0, 1, 1, 1}, // O
+		{1, 1, 0, 2}, // X wins the center board
+	}
+	for _, move := range moves {
+		if !g.IsLegal(move) {
+			t.Fatalf("move %v should be legal", move)
+		}
+		g.Play(move)
+	}
+	if g.meta.GetToken(1, 1) != "X" {
+		t.Fatalf("expected X to win the center board")
+	}
+
+	// O is sent to board 0,2, and may never be sent to the won center board
+	g.Play(Move{0, 2, 1, 1})
+	if _, _, forced := g.ForcedBoard(); forced {
+		t.Error("a move sending the opponent to a won board should free them")
+	}
+	for _, move := range g.Moves() {
+		if move.BoardRow == 1 && move.BoardCol == 1 {
+			t.Errorf("move %v is on a won board", move)
+		}
+	}
+
+	// Undoing the moves restores the empty game
+	g.Undo(Move{0, 2, 1, 1})
+	for i := len(moves) - 1; i >= 0; i-- {
+		g.Undo(moves[i])
+	}
+	if g.meta.GetToken(1, 1) != " " || len(g.Moves()) != 81 || g.PlayerToMove() != 0 {
+		t.Error("undo did not restore the empty game")
+	}
+}
+
+func TestPlayMove(t *testing.T) {
+	g := NewGame()
+	if err := g.PlayMove("2,2\n"); err == nil {
+		t.Error("the board can only be left out when it is forced")
+	}
+	if err := g.PlayMove("1,1,2,0\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.PlayMove("0,0\n"); err != nil {
+		t.Fatal(err)
+	}
+	if g.boards[2][0].GetToken(0, 0) != "O" {
+		t.Error("expected O on board 2,0")
+	}
+	if err := g.PlayMove("1,1,1,1\n"); err == nil {
+		t.Error("expected an error playing off the forced board")
+	}
+}
+
+func TestAITakesTheWin(t *testing.T) {
+	g := NewGame()
+
+	// X holds the top two corner boards and can take the third with the
+	// next move on board 0,1
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if (i == 0 && j != 1) || (i == 1 && j == 0) {
+				g.boards[0][0].PlaceToken(i, j, "X")
+				g.boards[0][2].PlaceToken(i, j, "X")
+			}
+		}
+	}
+	g.meta.PlaceToken(0, 0, "X")
+	g.meta.PlaceToken(0, 2, "X")
+	g.boards[0][1].PlaceToken(0, 0, "X")
+	g.boards[0][1].PlaceToken(0, 1, "X")
+	g.forcedRow, g.forcedCol = 0, 1
+
+	if err := g.PlayAIMove(context.Background(), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if over, winner := g.Result(); !over || winner != 0 {
+		t.Fatalf("expected X to win, got over=%v winner=%d", over, winner)
+	}
+	if over, outcome := g.Outcome(); !over || outcome != minmax.Loss {
+		t.Errorf("expected a loss for O, got %v", outcome)
+	}
+}
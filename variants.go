@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackmcdermo/tic-tac-toe-/game"
+	"github.com/jackmcdermo/tic-tac-toe-/gomoku"
+	"github.com/jackmcdermo/tic-tac-toe-/morris"
+	"github.com/jackmcdermo/tic-tac-toe-/notakto"
+	"github.com/jackmcdermo/tic-tac-toe-/quantum"
+	"github.com/jackmcdermo/tic-tac-toe-/qubic"
+	"github.com/jackmcdermo/tic-tac-toe-/ultimate"
+)
+
+const PLAYERS_PROMPT = "Enter '1' to play against a friend, '2' to play against the AI,  or '3' for two AI players to square off!"
+
+// variantGame is implemented by the game types of the variant packages, so
+// the CLI can run all of them the same way.
+type variantGame interface {
+	// PrintBoard prints the current position.
+	PrintBoard()
+	// MoveFormat describes the move input expected from the player to move.
+	MoveFormat() string
+	// PlayMove parses a move entered by a human and plays it.
+	PlayMove(input string) error
+	// PlayAIMove searches for a move and plays it. With a time budget the
+	// search deepens until it runs out, otherwise the difficulty sets the depth.
+	PlayAIMove(ctx context.Context, difficulty int, budget time.Duration) error
+	// PlayerToMove returns the index of the player to move.
+	PlayerToMove() int
+	// Result reports whether the game is over and the index of the winner,
+	// or -1 for a draw.
+	Result() (bool, int)
+}
+
+// variant is a game mode offered next to the classic game in the new game menu.
+type variant struct {
+	key     string // what the user enters to pick the variant
+	name    string
+	newGame func() variantGame
+}
+
+var variants = []variant{
+	{"u", "Ultimate tic-tac-toe", func() variantGame { return ultimate.NewGame() }},
+	{"c", "Qubic (4x4x4 cube)", func() variantGame { return qubic.NewGame() }},
+	{"g", "Gomoku (15x15, five or more in a row)", func() variantGame { return gomoku.NewGame(15, false) }},
+	{"s", "standard Gomoku (15x15, exactly five in a row)", func() variantGame { return gomoku.NewGame(15, true) }},
+	{"l", "Gomoku on a 19x19 board", func() variantGame { return gomoku.NewGame(19, false) }},
+	{"n", "Notakto (three boards, both players place X)", func() variantGame { return notakto.NewGame(3) }},
+	{"e", "Quantum tic-tac-toe (entangled marks in two squares)", func() variantGame { return quantum.NewGame() }},
+	{"t", "Three Men's Morris (place three pieces, then slide them)", func() variantGame { return morris.NewGame() }},
+}
+
+// variantSession is the variant being played, from picking the players until
+// the game is over.
+type variantSession struct {
+	variant variant
+	game    variantGame
+	players []game.Player // nil until the user picks who plays
+}
+
+// session is the running variant, nil when none is.
+var session *variantSession
+
+// printNewGamePrompt prints the classic game modes followed by the variants.
+func printNewGamePrompt() {
+	fmt.Println(NEW_GAME_PROMPT)
+
+	fmt.Printf("Enter 'm' to turn misère rules, where three in a row loses, on or off (now %s).\n", onOff(misereRules))
+	fmt.Printf("Enter 'w' to turn Wild rules, where either player may place X or O, on or off (now %s).\n", onOff(wildRules))
+	fmt.Printf("Enter 'f' to turn Numerical rules, where players place numbers and a line adding up to 15 wins, on or off (now %s).\n", onOff(numericalRules))
+	fmt.Printf("Enter 'o' to turn Order and Chaos, where Order wants five in a row of X or O and Chaos a full board without one, on or off (now %s).\n", onOff(orderChaosRules))
+	fmt.Printf("Enter 'p' to switch between two and three players, X, O and △ (now %d).\n", playerCount)
+	if boardLayout != "" {
+		fmt.Printf("Enter 'b' to change the board (now %s, laid out as %s).\n", boardRules, boardLayout)
+	} else {
+		fmt.Printf("Enter 'b' to change the board (now %s).\n", boardRules)
+	}
+
+	var options []string
+	for _, v := range variants {
+		options = append(options, fmt.Sprintf("'%s' for %s", v.key, v.name))
+	}
+	fmt.Printf("Or enter %s.\n", strings.Join(options, ", "))
+}
+
+func onOff(setting bool) string {
+	if setting {
+		return "on"
+	}
+	return "off"
+}
+
+func findVariant(input string) (variant, bool) {
+	for _, v := range variants {
+		if strings.TrimSpace(input) == v.key {
+			return v, true
+		}
+	}
+	return variant{}, false
+}
+
+// startVariant starts a session of the variant and asks who is playing.
+func startVariant(v variant) {
+	session = &variantSession{variant: v, game: v.newGame()}
+	fmt.Printf("Let's play %s!\n", v.name)
+	fmt.Println(PLAYERS_PROMPT)
+}
+
+// handleVariantInput handles the user's input while a variant is being played.
+func handleVariantInput(input string) {
+
+	// Check if the user wants to quit
+	if input == "q\n" {
+		fmt.Println("Thanks for playing!")
+		os.Exit(0)
+	}
+
+	if session.players == nil {
+		handleVariantPlayers(input)
+		return
+	}
+
+	for i := range session.players {
+		if playerNeedsAILevel(session.players[i]) {
+			handleVariantAILevel(&session.players[i], input)
+			return
+		}
+	}
+
+	handleVariantMove(input)
+}
+
+// handleVariantPlayers sets up the players the user picked.
+func handleVariantPlayers(input string) {
+	switch input {
+	case "1\n": // Two human players
+		session.players = newPlayers(2, 2)
+	case "2\n": // Player vs AI
+		session.players = newPlayers(2, 1)
+	case "3\n": // AI vs AI
+		session.players = newPlayers(2, 0)
+	default:
+		fmt.Printf("Invalid input. %s\n", PLAYERS_PROMPT)
+		return
+	}
+	promptVariantAILevelOrStart()
+}
+
+func handleVariantAILevel(player *game.Player, input string) {
+	difficulty, err := parseAILevel(input)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	player.AiPlayerDifficulty = difficulty
+	promptVariantAILevelOrStart()
+}
+
+// promptVariantAILevelOrStart asks for the next AI level that isn't set, or
+// starts the game once all are.
+func promptVariantAILevelOrStart() {
+	for _, player := range session.players {
+		if playerNeedsAILevel(player) {
+			printAILevelPrompt(player.Name, false)
+			return
+		}
+	}
+	session.game.PrintBoard()
+	printVariantMovePrompt()
+}
+
+// handleVariantMove plays the move of the player to move. The AI moves when
+// the user presses enter.
+func handleVariantMove(input string) {
+	player := session.players[session.game.PlayerToMove()]
+
+	if player.IsAI {
+		fmt.Println("AI player is making a move...")
+		ctx := startAIMove()
+		err := session.game.PlayAIMove(ctx, player.AiPlayerDifficulty, moveTimeLimit)
+		stopAIMove()
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("AI move cancelled.")
+			return
+		} else if err != nil {
+			fmt.Printf("The AI player couldn't move: %v\n", err)
+			printVariantMovePrompt()
+			return
+		}
+	} else if err := session.game.PlayMove(input); err != nil {
+		fmt.Println(err)
+		printVariantMovePrompt()
+		return
+	}
+
+	session.game.PrintBoard()
+	if over, winner := session.game.Result(); over {
+		if winner == -1 {
+			fmt.Println("Game over! It's a tie!")
+		} else {
+			fmt.Printf("Game over! %s wins!\n", session.players[winner].Name)
+		}
+		fmt.Println("")
+		session = nil
+		printNewGamePrompt()
+		return
+	}
+	printVariantMovePrompt()
+}
+
+func printVariantMovePrompt() {
+	player := session.players[session.game.PlayerToMove()]
+	if player.IsAI {
+		fmt.Println("Press enter for the AI player to go...")
+	} else {
+		fmt.Printf("%s, enter your move (%s): ", player.Name, session.game.MoveFormat())
+	}
+}